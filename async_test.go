@@ -0,0 +1,42 @@
+package lean_test
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/draganm/go-lean"
+	"github.com/draganm/go-lean/common/eventloop"
+	"github.com/go-logr/logr/testr"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAsyncHandlers(t *testing.T) {
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sfs, err := fs.Sub(simple, "fixtures/async")
+	require.NoError(err)
+
+	w, err := lean.Construct(ctx, sfs, testr.New(t), map[string]any{
+		"fetchValue": func(loop *eventloop.EventLoop) *goja.Promise {
+			return loop.Promise(func() (any, error) {
+				return "go", nil
+			})
+		},
+	})
+	require.NoError(err)
+
+	require.HTTPStatusCode(w.ServeHTTP, "GET", "/wait", nil, 200)
+	require.HTTPBodyContains(w.ServeHTTP, "GET", "/wait", nil, "waited for go")
+
+	require.HTTPStatusCode(w.ServeHTTP, "GET", "/rejected", nil, 418)
+
+	metrics := findMetrics(t, "async_value", dto.MetricType_GAUGE)
+	require.Len(metrics, 1)
+	require.Equal(7.0, metrics[0].GetGauge().GetValue())
+}
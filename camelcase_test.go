@@ -0,0 +1,34 @@
+package lean_test
+
+import (
+	"context"
+	"io/fs"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/draganm/go-lean"
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCamelCase(t *testing.T) {
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sfs, err := fs.Sub(simple, "fixtures/camelcase")
+	require.NoError(err)
+
+	w, err := lean.Construct(ctx, sfs, testr.New(t), map[string]any{}, lean.WithCamelCase())
+	require.NoError(err)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/echo", nil)
+	req.Header.Set("X-Name", "lean")
+	w.ServeHTTP(rec, req)
+
+	require.Equal(200, rec.Code)
+	require.Equal("GET /echo lean", rec.Body.String())
+	require.Equal("text/plain", rec.Header().Get("Content-Type"))
+}
@@ -0,0 +1,194 @@
+package compiler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja/ast"
+	"github.com/dop251/goja/parser"
+	"github.com/dop251/goja/token"
+	"github.com/evanw/esbuild/pkg/api"
+)
+
+type Options struct {
+	// Banner and Footer are added around the transpiled code,
+	// source map takes them into account.
+	Banner string
+	Footer string
+}
+
+// Transpile converts TypeScript and ES module syntax (import/export)
+// into a CommonJS script that goja can run.
+// The result contains an inline source map pointing to the original source.
+func Transpile(name string, src string, opts Options) (string, error) {
+	loader := api.LoaderJS
+	if strings.HasSuffix(name, ".ts") {
+		loader = api.LoaderTS
+	}
+
+	res := api.Transform(src, api.TransformOptions{
+		Loader:         loader,
+		Format:         api.FormatCommonJS,
+		Target:         api.ES2020,
+		Sourcefile:     name,
+		Sourcemap:      api.SourceMapInline,
+		SourcesContent: api.SourcesContentInclude,
+		Banner:         opts.Banner,
+		Footer:         opts.Footer,
+		LogLevel:       api.LogLevelSilent,
+	})
+
+	if len(res.Errors) > 0 {
+		errs := []error{}
+		for _, m := range res.Errors {
+			errs = append(errs, messageError(m))
+		}
+		return "", fmt.Errorf("could not transpile %s: %w", name, errors.Join(errs...))
+	}
+
+	return string(res.Code), nil
+}
+
+func messageError(m api.Message) error {
+	if m.Location == nil {
+		return errors.New(m.Text)
+	}
+	return fmt.Errorf("%s:%d:%d: %s", m.Location.File, m.Location.Line, m.Location.Column+1, m.Text)
+}
+
+// Compile transpiles and compiles a script.
+func Compile(name string, src string, strict bool) (*goja.Program, error) {
+	code, err := Transpile(name, src, Options{})
+	if err != nil {
+		return nil, err
+	}
+
+	return goja.Compile(name, code, strict)
+}
+
+// RunScript runs a program created by Compile. Everything the script
+// exports using ES module syntax is set as a global, so that
+// `export function handler() {}` can be used instead of a global function.
+func RunScript(rt *goja.Runtime, prog *goja.Program) error {
+	global := rt.GlobalObject()
+
+	module := rt.NewObject()
+	exports := rt.NewObject()
+	err := module.Set("exports", exports)
+	if err != nil {
+		return fmt.Errorf("could not set module exports: %w", err)
+	}
+
+	for k, v := range map[string]goja.Value{"module": module, "exports": exports} {
+		err = global.Set(k, v)
+		if err != nil {
+			return fmt.Errorf("could not set %s: %w", k, err)
+		}
+	}
+
+	defer func() {
+		global.Delete("module")
+		global.Delete("exports")
+	}()
+
+	_, err = rt.RunProgram(prog)
+	if err != nil {
+		return err
+	}
+
+	exported := module.Get("exports")
+	if exported == nil || goja.IsUndefined(exported) || goja.IsNull(exported) {
+		return nil
+	}
+
+	exportedObject := exported.ToObject(rt)
+	for _, k := range exportedObject.Keys() {
+		err = global.Set(k, exportedObject.Get(k))
+		if err != nil {
+			return fmt.Errorf("could not set exported %s as global: %w", k, err)
+		}
+	}
+
+	return nil
+}
+
+// Binding is a top-level lexical declaration of a script.
+type Binding struct {
+	Name string
+	// Mutable is false for const declarations.
+	Mutable bool
+}
+
+// LexicalBindings returns the top-level let, const and class declarations of a script.
+// Unlike var and function declarations, they are not properties of the global object,
+// so they can only be read and written by code running in the same runtime.
+func LexicalBindings(name string, src string) ([]Binding, error) {
+	code, err := Transpile(name, src, Options{})
+	if err != nil {
+		return nil, err
+	}
+
+	prog, err := parser.ParseFile(nil, name, code, 0)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", name, err)
+	}
+
+	bindings := []Binding{}
+	for _, st := range prog.Body {
+		switch st := st.(type) {
+		case *ast.LexicalDeclaration:
+			for _, b := range st.List {
+				for _, n := range boundNames(b.Target) {
+					bindings = append(bindings, Binding{Name: n, Mutable: st.Token == token.LET})
+				}
+			}
+		case *ast.ClassDeclaration:
+			if st.Class.Name != nil {
+				bindings = append(bindings, Binding{Name: st.Class.Name.Name.String(), Mutable: true})
+			}
+		}
+	}
+
+	return bindings, nil
+}
+
+// boundNames returns identifiers bound by a declaration, including destructuring patterns.
+func boundNames(target ast.Node) []string {
+	switch t := target.(type) {
+	case *ast.Identifier:
+		return []string{t.Name.String()}
+	case *ast.AssignExpression:
+		return boundNames(t.Left)
+	case *ast.SpreadElement:
+		return boundNames(t.Expression)
+	case *ast.ArrayPattern:
+		names := []string{}
+		for _, e := range t.Elements {
+			if e != nil {
+				names = append(names, boundNames(e)...)
+			}
+		}
+		if t.Rest != nil {
+			names = append(names, boundNames(t.Rest)...)
+		}
+		return names
+	case *ast.ObjectPattern:
+		names := []string{}
+		for _, p := range t.Properties {
+			switch p := p.(type) {
+			case *ast.PropertyShort:
+				names = append(names, p.Name.Name.String())
+			case *ast.PropertyKeyed:
+				names = append(names, boundNames(p.Value)...)
+			}
+		}
+		if t.Rest != nil {
+			names = append(names, boundNames(t.Rest)...)
+		}
+		return names
+	default:
+		return nil
+	}
+}
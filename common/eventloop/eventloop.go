@@ -0,0 +1,323 @@
+package eventloop
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+var ErrPromiseNotSettled = errors.New("promise was not settled")
+
+// EventLoop runs timers and promises resolved from Go on top of a goja runtime.
+// Unlike an event loop that owns its runtime, it is driven by Run on the
+// caller's goroutine, which makes it usable with pooled runtimes.
+// Everything scheduled by one Run is discarded when Run returns.
+type EventLoop struct {
+	rt    *goja.Runtime
+	throw goja.Callable
+
+	mu          sync.Mutex
+	queue       []job
+	wakeup      chan struct{}
+	pending     int
+	generation  uint64
+	timers      map[int64]*timer
+	nextTimerID int64
+}
+
+type job struct {
+	generation uint64
+	fn         func() error
+}
+
+type timer struct {
+	t        *time.Timer
+	fn       goja.Callable
+	args     []goja.Value
+	interval time.Duration
+	repeat   bool
+}
+
+// New creates an event loop for the runtime and installs
+// `setTimeout`, `clearTimeout`, `setInterval`, `clearInterval`
+// and `setImmediate` globals.
+func New(rt *goja.Runtime) (*EventLoop, error) {
+	l := &EventLoop{
+		rt:     rt,
+		wakeup: make(chan struct{}, 1),
+		timers: map[int64]*timer{},
+	}
+
+	throw, err := rt.RunString("(function(e) { throw e })")
+	if err != nil {
+		return nil, fmt.Errorf("could not create throw function: %w", err)
+	}
+
+	var isFunction bool
+	l.throw, isFunction = goja.AssertFunction(throw)
+	if !isFunction {
+		return nil, errors.New("throw is not a function")
+	}
+
+	for name, fn := range map[string]func(goja.FunctionCall) goja.Value{
+		"setTimeout":    l.setTimeout,
+		"setInterval":   l.setInterval,
+		"setImmediate":  l.setImmediate,
+		"clearTimeout":  l.clearTimer,
+		"clearInterval": l.clearTimer,
+	} {
+		err = rt.Set(name, fn)
+		if err != nil {
+			return nil, fmt.Errorf("could not set %s: %w", name, err)
+		}
+	}
+
+	return l, nil
+}
+
+// Runtime returns the runtime the loop is running on.
+func (l *EventLoop) Runtime() *goja.Runtime {
+	return l.rt
+}
+
+// Run calls fn and then runs the loop until there are no more pending
+// timers and promises, or the context is done.
+// If fn returns a promise, its result is returned once it is settled.
+// A rejected promise is returned as *goja.Exception, as if the
+// rejection reason has been thrown.
+func (l *EventLoop) Run(ctx context.Context, fn func(rt *goja.Runtime) (goja.Value, error)) (goja.Value, error) {
+	defer l.reset()
+
+	l.mu.Lock()
+	generation := l.generation
+	l.mu.Unlock()
+
+	v, err := fn(l.rt)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		err = ctx.Err()
+		if err != nil {
+			return nil, err
+		}
+
+		l.mu.Lock()
+		jobs := l.queue
+		l.queue = nil
+		pending := l.pending
+		l.mu.Unlock()
+
+		if len(jobs) == 0 {
+			if pending == 0 {
+				break
+			}
+
+			select {
+			case <-l.wakeup:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			continue
+		}
+
+		for _, j := range jobs {
+			if j.generation != generation {
+				continue
+			}
+
+			err = j.fn()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return l.settle(v)
+}
+
+func (l *EventLoop) settle(v goja.Value) (goja.Value, error) {
+	if v == nil {
+		return v, nil
+	}
+
+	p, isPromise := v.Export().(*goja.Promise)
+	if !isPromise {
+		return v, nil
+	}
+
+	switch p.State() {
+	case goja.PromiseStateFulfilled:
+		return p.Result(), nil
+	case goja.PromiseStateRejected:
+		_, err := l.throw(nil, p.Result())
+		return nil, err
+	default:
+		return nil, ErrPromiseNotSettled
+	}
+}
+
+// Hold keeps the loop running until the returned function is called.
+// The returned function schedules fn to be run on the loop, it may be
+// called from any goroutine, but only once.
+func (l *EventLoop) Hold() func(fn func(rt *goja.Runtime) error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pending++
+	generation := l.generation
+
+	return func(fn func(rt *goja.Runtime) error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if generation != l.generation {
+			return
+		}
+		l.pending--
+		l.enqueueLocked(generation, func() error {
+			return fn(l.rt)
+		})
+	}
+}
+
+// Promise calls fn in a new goroutine and returns a promise that
+// is resolved with fn's result on the loop.
+func (l *EventLoop) Promise(fn func() (any, error)) *goja.Promise {
+	p, resolve, reject := l.rt.NewPromise()
+	done := l.Hold()
+	go func() {
+		res, err := fn()
+		done(func(rt *goja.Runtime) error {
+			if err != nil {
+				reject(rt.NewGoError(err))
+				return nil
+			}
+			resolve(res)
+			return nil
+		})
+	}()
+	return p
+}
+
+func (l *EventLoop) enqueueLocked(generation uint64, fn func() error) {
+	l.queue = append(l.queue, job{generation: generation, fn: fn})
+	select {
+	case l.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+func (l *EventLoop) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, t := range l.timers {
+		t.t.Stop()
+	}
+	l.timers = map[int64]*timer{}
+	l.queue = nil
+	l.pending = 0
+	l.generation++
+}
+
+func (l *EventLoop) schedule(call goja.FunctionCall, repeat bool) goja.Value {
+	fn, isFunction := goja.AssertFunction(call.Argument(0))
+	if !isFunction {
+		panic(l.rt.NewTypeError("callback must be a function"))
+	}
+
+	delay := time.Duration(call.Argument(1).ToInteger()) * time.Millisecond
+	if delay < 0 {
+		delay = 0
+	}
+
+	var args []goja.Value
+	if len(call.Arguments) > 2 {
+		args = append(args, call.Arguments[2:]...)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.nextTimerID++
+	id := l.nextTimerID
+	generation := l.generation
+
+	t := &timer{
+		fn:       fn,
+		args:     args,
+		interval: delay,
+		repeat:   repeat,
+	}
+
+	var fire func()
+	fire = func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if generation != l.generation {
+			return
+		}
+		l.enqueueLocked(generation, func() error {
+			l.mu.Lock()
+			_, active := l.timers[id]
+			if active {
+				if t.repeat {
+					t.t = time.AfterFunc(t.interval, fire)
+				} else {
+					delete(l.timers, id)
+					l.pending--
+				}
+			}
+			l.mu.Unlock()
+
+			if !active {
+				return nil
+			}
+
+			_, err := t.fn(nil, t.args...)
+			return err
+		})
+	}
+
+	t.t = time.AfterFunc(delay, fire)
+	l.timers[id] = t
+	l.pending++
+
+	return l.rt.ToValue(id)
+}
+
+func (l *EventLoop) setTimeout(call goja.FunctionCall) goja.Value {
+	return l.schedule(call, false)
+}
+
+func (l *EventLoop) setInterval(call goja.FunctionCall) goja.Value {
+	return l.schedule(call, true)
+}
+
+func (l *EventLoop) setImmediate(call goja.FunctionCall) goja.Value {
+	args := []goja.Value{call.Argument(0), l.rt.ToValue(0)}
+	if len(call.Arguments) > 1 {
+		args = append(args, call.Arguments[1:]...)
+	}
+
+	return l.schedule(goja.FunctionCall{This: call.This, Arguments: args}, false)
+}
+
+func (l *EventLoop) clearTimer(call goja.FunctionCall) goja.Value {
+	id := call.Argument(0).ToInteger()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	t, found := l.timers[id]
+	if found {
+		t.t.Stop()
+		delete(l.timers, id)
+		l.pending--
+	}
+
+	return goja.Undefined()
+}
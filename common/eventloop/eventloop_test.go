@@ -0,0 +1,77 @@
+package eventloop
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunWaitsForTimersAndPromises(t *testing.T) {
+	require := require.New(t)
+
+	rt := goja.New()
+	l, err := New(rt)
+	require.NoError(err)
+
+	rt.Set("fromGo", func() *goja.Promise {
+		return l.Promise(func() (any, error) {
+			time.Sleep(5 * time.Millisecond)
+			return 40, nil
+		})
+	})
+
+	v, err := l.Run(context.Background(), func(rt *goja.Runtime) (goja.Value, error) {
+		return rt.RunString(`
+			(async () => {
+				const v = await fromGo()
+				await new Promise((resolve) => setTimeout(resolve, 5))
+				return v + 2
+			})()
+		`)
+	})
+	require.NoError(err)
+	require.Equal(int64(42), v.ToInteger())
+}
+
+func TestRunReturnsRejectionAsException(t *testing.T) {
+	require := require.New(t)
+
+	rt := goja.New()
+	l, err := New(rt)
+	require.NoError(err)
+
+	_, err = l.Run(context.Background(), func(rt *goja.Runtime) (goja.Value, error) {
+		return rt.RunString(`Promise.reject(new Error("boom"))`)
+	})
+
+	exc := &goja.Exception{}
+	require.True(errors.As(err, &exc))
+	require.Contains(exc.Value().String(), "boom")
+}
+
+func TestRunStopsWhenContextIsDone(t *testing.T) {
+	require := require.New(t)
+
+	rt := goja.New()
+	l, err := New(rt)
+	require.NoError(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = l.Run(ctx, func(rt *goja.Runtime) (goja.Value, error) {
+		return rt.RunString(`setInterval(() => {}, 1)`)
+	})
+	require.ErrorIs(err, context.DeadlineExceeded)
+
+	// timers of the previous run must not leak into the next one
+	v, err := l.Run(context.Background(), func(rt *goja.Runtime) (goja.Value, error) {
+		return rt.RunString(`1`)
+	})
+	require.NoError(err)
+	require.Equal(int64(1), v.ToInteger())
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 )
 
 type Globals map[string]any
@@ -34,78 +35,182 @@ func (g Globals) Merge(other Globals) (res Globals, err error) {
 
 }
 
-func autoWireFunction(v any, values ...any) (any, error) {
+// Finish is returned by providers that hold resources for the duration of
+// an invocation. It is called with the error of the invocation once it is done,
+// e.g. to commit a transaction on success and roll it back on failure.
+type Finish func(err error) error
+
+// bind returns the values injected for the types, every value is bound to the first
+// type it is assignable to. Types without a value, e.g. the request while a handler
+// script is being evaluated, are bound to the zero Value.
+func bind(types []reflect.Type, values []any) ([]reflect.Value, error) {
+	bound := make([]reflect.Value, len(types))
+	for _, v := range values {
+		vt := reflect.TypeOf(v)
+		if vt == nil {
+			continue
+		}
+
+		i := 0
+		for ; i < len(types); i++ {
+			if vt.AssignableTo(types[i]) {
+				break
+			}
+		}
+
+		if i == len(types) {
+			return nil, fmt.Errorf("value of type %s is not one of the injectable types", vt)
+		}
+
+		if bound[i].IsValid() {
+			return nil, fmt.Errorf("more than one value of type %s", types[i])
+		}
+
+		bound[i] = reflect.ValueOf(v)
+	}
+	return bound, nil
+}
+
+func autoWireFunction(v any, types []reflect.Type, values ...any) (any, error) {
+	wired, finish, available, err := autoWireProvider(v, types, values)
+	if err != nil {
+		return nil, err
+	}
+
+	if !available {
+		return nil, errors.New("not all injected parameters can be bound")
+	}
+
+	if finish != nil {
+		return nil, errors.New("provider returns a finish function, it has to be wired with AutoWireFinish")
+	}
+
+	return wired, nil
+}
+
+// autoWireProvider binds leading parameters of v to the values of the injectable types,
+// see plan for the rules. Parameters are matched against the declared types, not the
+// types of the values, so that they are bound the same way ForContext expects.
+// A provider of Values with all parameters bound is called, it may return a finish
+// function and an error:
+//
+//	func(...) Values
+//	func(...) (Values, error)
+//	func(...) (Values, func(error))
+//	func(...) (Values, func(error) error)
+//	func(...) (Values, func(error), error)
+//	func(...) (Values, func(error) error, error)
+//
+// Globals with parameters bound to types without a value are not available,
+// e.g. globals using the request while the script is being evaluated.
+func autoWireProvider(v any, types []reflect.Type, values []any) (wired any, finish Finish, available bool, err error) {
 	rv := reflect.ValueOf(v)
 
 	if rv.Kind() != reflect.Func {
-		return v, nil
+		return v, nil, true, nil
 	}
 
 	t := rv.Type()
 
-	argCount := t.NumIn()
-	bound := []reflect.Value{}
-outer:
-	for i := 0; i < argCount; i++ {
-		it := t.In(i)
-
-		for _, av := range values {
-			avVal := reflect.ValueOf(av)
-			if avVal.Type().AssignableTo(it) {
-				bound = append(bound, avVal)
-				continue outer
-			}
-		}
-
-		break
+	injected, err := bind(types, values)
+	if err != nil {
+		return nil, nil, false, err
 	}
 
-	// if len(bound) == 0 {
-	// 	return v
-	// }
-
-	in := []reflect.Type{}
-	for i := len(bound); i < t.NumIn(); i++ {
-		in = append(in, t.In(i))
+	bindings, next, err := plan(t, types)
+	if err != nil {
+		return nil, nil, false, err
 	}
 
-	out := []reflect.Type{}
-	for i := 0; i < t.NumOut(); i++ {
-		out = append(out, t.Out(i))
+	bound := []reflect.Value{}
+	for _, b := range bindings {
+		if !injected[b].IsValid() {
+			return nil, nil, false, nil
+		}
+		bound = append(bound, injected[b])
 	}
 
-	ft := reflect.FuncOf(in, out, t.IsVariadic())
+	if isProvider(t) {
+		if next < t.NumIn() {
+			return nil, nil, false, nil
+		}
 
-	if len(in) == 0 && len(out) > 0 && out[0] == valuesType {
 		res := rv.Call(bound)
-		if len(out) > 1 {
+		if t.NumOut() > 1 {
 			// check for error
-			lastType := out[len(out)-1]
+			lastType := t.Out(t.NumOut() - 1)
 			if lastType == errorType {
-				lv := res[len(out)-1]
+				lv := res[t.NumOut()-1]
 				if !lv.IsNil() {
-					return nil, lv.Interface().(error)
+					return nil, nil, false, lv.Interface().(error)
 				}
 			}
 		}
 
-		return res[0].Interface().(Values), nil
+		if t.NumOut() > 1 && isFinishType(t.Out(1)) && !res[1].IsNil() {
+			finish = toFinish(res[1])
+		}
+
+		return res[0].Interface().(Values), finish, true, nil
+
+	}
+
+	in := []reflect.Type{}
+	for i := len(bound); i < t.NumIn(); i++ {
+		in = append(in, t.In(i))
+	}
 
+	out := []reflect.Type{}
+	for i := 0; i < t.NumOut(); i++ {
+		out = append(out, t.Out(i))
 	}
 
+	ft := reflect.FuncOf(in, out, t.IsVariadic())
+
 	return reflect.MakeFunc(ft, func(args []reflect.Value) (results []reflect.Value) {
 		realArgs := make([]reflect.Value, len(args)+len(bound))
 		copy(realArgs, bound)
 		copy(realArgs[len(bound):], args)
+		if t.IsVariadic() {
+			return rv.CallSlice(realArgs)
+		}
 		return rv.Call(realArgs)
-	}).Interface(), nil
+	}).Interface(), nil, true, nil
 
 }
 
-func (g Globals) AutoWire(vals ...any) (Globals, error) {
+func isProvider(t reflect.Type) bool {
+	return t.NumOut() > 0 && t.Out(0) == valuesType
+}
+
+func isFinishType(t reflect.Type) bool {
+	if t.Kind() != reflect.Func || t.NumIn() != 1 || t.In(0) != errorType {
+		return false
+	}
+	return t.NumOut() == 0 || (t.NumOut() == 1 && t.Out(0) == errorType)
+}
+
+func toFinish(fn reflect.Value) Finish {
+	return func(err error) error {
+		errValue := reflect.Zero(errorType)
+		if err != nil {
+			errValue = reflect.ValueOf(err)
+		}
+
+		res := fn.Call([]reflect.Value{errValue})
+		if len(res) == 0 || res[0].IsNil() {
+			return nil
+		}
+		return res[0].Interface().(error)
+	}
+}
+
+// AutoWire binds leading parameters of functions to the values of the injectable types
+// and calls providers. Types are usually the InjectableTypes of the context the script runs in.
+func (g Globals) AutoWire(types []reflect.Type, vals ...any) (Globals, error) {
 	res := Globals{}
 	for k, v := range g {
-		wv, err := autoWireFunction(v, vals...)
+		wv, err := autoWireFunction(v, types, vals...)
 		if err != nil {
 			return nil, fmt.Errorf("could not autowire %s: %w", k, err)
 		}
@@ -115,7 +220,50 @@ func (g Globals) AutoWire(vals ...any) (Globals, error) {
 	return res, nil
 }
 
+// AutoWireFinish works like AutoWire, but allows providers to return a finish function.
+// The returned Finish calls finish functions of all providers in reverse order of their names
+// and has to be called once the invocation is done. If wiring fails, finish functions of
+// providers that have already been called are called with the error.
+func (g Globals) AutoWireFinish(types []reflect.Type, vals ...any) (Globals, Finish, error) {
+	names := make([]string, 0, len(g))
+	for k := range g {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	res := Globals{}
+	finishers := []Finish{}
+
+	finish := func(err error) error {
+		errs := []error{}
+		for i := len(finishers) - 1; i >= 0; i-- {
+			ferr := finishers[i](err)
+			if ferr != nil {
+				errs = append(errs, ferr)
+			}
+		}
+		return errors.Join(errs...)
+	}
+
+	for _, k := range names {
+		wv, f, available, err := autoWireProvider(g[k], types, vals)
+		if err != nil {
+			err = fmt.Errorf("could not autowire %s: %w", k, err)
+			return nil, nil, errors.Join(err, finish(err))
+		}
+		if !available {
+			continue
+		}
+		if f != nil {
+			finishers = append(finishers, f)
+		}
+		res[k] = wv
+	}
+
+	return res, finish, nil
+}
+
 type Values map[string]any
 
 var valuesType = reflect.TypeOf(Values{})
-var errorType = reflect.TypeOf(errors.New(""))
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
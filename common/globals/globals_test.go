@@ -2,12 +2,20 @@ package globals
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/dop251/goja"
 	"github.com/stretchr/testify/require"
 )
 
+var testTypes = []reflect.Type{TypeOf[context.Context](), TypeOf[*goja.Runtime]()}
+
 func TestAutoWireFunction(t *testing.T) {
 	require := require.New(t)
 
@@ -22,7 +30,7 @@ func TestAutoWireFunction(t *testing.T) {
 		passedRT = rt
 	}
 
-	wired, err := autoWireFunction(fn, ctx, rt)
+	wired, err := autoWireFunction(fn, testTypes, ctx, rt)
 	require.NoError(err)
 
 	rt.Set("foo", wired)
@@ -47,7 +55,7 @@ func TestAutoWireFunctionProvidingValuesObject(t *testing.T) {
 		}
 	}
 
-	wired, err := autoWireFunction(fn, ctx, rt)
+	wired, err := autoWireFunction(fn, testTypes, ctx, rt)
 	require.NoError(err)
 
 	rt.Set("foo", wired)
@@ -58,3 +66,205 @@ func TestAutoWireFunctionProvidingValuesObject(t *testing.T) {
 	require.Equal(res.ToInteger(), int64(3))
 
 }
+
+func TestAutoWireFinish(t *testing.T) {
+	require := require.New(t)
+
+	ctx := context.Background()
+	finished := []string{}
+
+	g := Globals{
+		"a": func(ctx context.Context) (Values, func(error)) {
+			return Values{"name": "a"}, func(err error) {
+				finished = append(finished, fmt.Sprintf("a: %v", err))
+			}
+		},
+		"b": func(ctx context.Context) (Values, func(error) error, error) {
+			return Values{"name": "b"}, func(err error) error {
+				finished = append(finished, fmt.Sprintf("b: %v", err))
+				return errors.New("b failed")
+			}, nil
+		},
+		"c": func(ctx context.Context) Values {
+			return Values{"name": "c"}
+		},
+	}
+
+	wired, finish, err := g.AutoWireFinish(testTypes, ctx)
+	require.NoError(err)
+	require.Len(wired, 3)
+
+	err = finish(errors.New("handler failed"))
+	require.EqualError(err, "b failed")
+	require.Equal([]string{"b: handler failed", "a: handler failed"}, finished)
+
+	_, err = g.AutoWire(testTypes, ctx)
+	require.Error(err)
+}
+
+func TestAutoWireFinishFailingProvider(t *testing.T) {
+	require := require.New(t)
+
+	ctx := context.Background()
+	finished := []error{}
+
+	g := Globals{
+		"a": func(ctx context.Context) (Values, func(error)) {
+			return Values{}, func(err error) {
+				finished = append(finished, err)
+			}
+		},
+		"b": func(ctx context.Context) (Values, error) {
+			return nil, errors.New("no connection")
+		},
+	}
+
+	_, _, err := g.AutoWireFinish(testTypes, ctx)
+	require.EqualError(err, "could not autowire b: no connection")
+	require.Len(finished, 1)
+	require.EqualError(finished[0], "could not autowire b: no connection")
+}
+
+type name string
+
+func TestAutoWireDoesNotInjectIntoAmbiguousParameters(t *testing.T) {
+	require := require.New(t)
+
+	ctx := context.Background()
+	rt := goja.New()
+
+	// string is neither equal to nor implemented by name, any is passed from JavaScript
+	fn := func(ctx context.Context, s string, v any) string {
+		return fmt.Sprintf("%s %v", s, v)
+	}
+
+	wired, err := autoWireFunction(fn, append(testTypes, TypeOf[name]()), ctx, name("injected"), rt)
+	require.NoError(err)
+
+	rt.Set("foo", wired)
+	res, err := rt.RunString("foo('a', 1)")
+	require.NoError(err)
+	require.Equal("a 1", res.String())
+
+	type closer interface{ Close() error }
+	_, err = autoWireFunction(func(c closer) {}, []reflect.Type{TypeOf[*nopCloser](), TypeOf[nopCloser]()}, &nopCloser{}, nopCloser{})
+	require.EqualError(err, "parameter 1 (globals.closer) is ambiguous, it matches *globals.nopCloser, globals.nopCloser")
+}
+
+func TestAutoWireBindsByDeclaredTypes(t *testing.T) {
+	require := require.New(t)
+
+	// the context implements fmt.Stringer, but context.Context doesn't,
+	// so the parameter is passed from JavaScript like ForContext expects
+	ctx := context.WithValue(context.Background(), name("key"), "value")
+	_, isStringer := ctx.(fmt.Stringer)
+	require.True(isStringer)
+
+	rt := goja.New()
+
+	describe := func(s fmt.Stringer) string {
+		return s.String()
+	}
+
+	wired, err := autoWireFunction(describe, testTypes, ctx, rt)
+	require.NoError(err)
+	require.Equal(1, reflect.TypeOf(wired).NumIn())
+
+	rt.Set("describe", wired)
+	rt.Set("duration", time.Second)
+	res, err := rt.RunString("describe(duration)")
+	require.NoError(err)
+	require.Equal("1s", res.String())
+
+	in := Injectables{"web": testTypes}
+	require.Equal(0, in.Injected(describe, "web"))
+}
+
+func TestAutoWireLeavesOutGlobalsWithoutValues(t *testing.T) {
+	require := require.New(t)
+
+	types := append(testTypes, TypeOf[*http.Request]())
+
+	g := Globals{
+		"path":    func(r *http.Request) string { return r.URL.Path },
+		"request": func(r *http.Request) Values { return Values{} },
+		"ctx":     func(ctx context.Context) Values { return Values{} },
+	}
+
+	wired, _, err := g.AutoWireFinish(types, context.Background(), goja.New())
+	require.NoError(err)
+	require.Equal([]string{"ctx"}, keys(wired))
+
+	_, _, err = g.AutoWireFinish(types, "not injectable")
+	require.EqualError(err, "could not autowire ctx: value of type string is not one of the injectable types")
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+func TestForContext(t *testing.T) {
+	require := require.New(t)
+
+	in := Injectables{
+		"web":  {TypeOf[context.Context](), TypeOf[*goja.Runtime](), TypeOf[*http.Request]()},
+		"cron": {TypeOf[context.Context](), TypeOf[*goja.Runtime](), TypeOf[name]()},
+	}
+
+	g := Globals{
+		"value":      42,
+		"everywhere": func(ctx context.Context, s string) {},
+		"request":    func(r *http.Request) Values { return nil },
+		"job":        func(ctx context.Context, n name, s string) {},
+	}
+
+	web, err := g.ForContext(in, "web")
+	require.NoError(err)
+	require.ElementsMatch([]string{"value", "everywhere", "request"}, keys(web))
+
+	cron, err := g.ForContext(in, "cron")
+	require.NoError(err)
+	require.ElementsMatch([]string{"value", "everywhere", "job"}, keys(cron))
+
+	_, err = Globals{"db": func(v fmt.Stringer) Values { return nil }}.ForContext(in, "web")
+	require.EqualError(err, "global db: parameter 1 (fmt.Stringer) can't be injected in any context")
+
+	_, err = Globals{"mixed": func(r *http.Request, n name) Values { return nil }}.ForContext(in, "web")
+	require.EqualError(err, "global mixed: parameters can't be injected in any single context")
+
+	type closer interface{ Close() error }
+	in["web"] = append(in["web"], TypeOf[io.ReadCloser](), TypeOf[*nopCloser]())
+	_, err = Globals{"close": func(c closer) {}}.ForContext(in, "web")
+	require.EqualError(err, "global close: web context: parameter 1 (globals.closer) is ambiguous, it matches io.ReadCloser, *globals.nopCloser")
+}
+
+func TestUsableIn(t *testing.T) {
+	require := require.New(t)
+
+	in := Injectables{
+		"web":  {TypeOf[context.Context](), TypeOf[*http.Request]()},
+		"cron": {TypeOf[context.Context](), TypeOf[name]()},
+	}
+
+	contexts, err := in.UsableIn(42)
+	require.NoError(err)
+	require.Equal([]string{"cron", "web"}, contexts)
+
+	job := func(ctx context.Context, n name, s string) {}
+	contexts, err = in.UsableIn(job)
+	require.NoError(err)
+	require.Equal([]string{"cron"}, contexts)
+	require.Equal(2, in.Injected(job, "cron"))
+	require.Equal(0, in.Injected(42, "cron"))
+
+	require.True(IsProvider(func(r *http.Request) Values { return nil }))
+	require.False(IsProvider(job))
+}
+
+func keys(g Globals) []string {
+	res := []string{}
+	for k := range g {
+		res = append(res, k)
+	}
+	return res
+}
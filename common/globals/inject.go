@@ -0,0 +1,224 @@
+package globals
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// TypeOf returns the type of T, including interface types:
+//
+//	globals.TypeOf[context.Context]()
+func TypeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// Injectables are the types that can be injected into providers,
+// per context scripts run in, e.g. "web", "cron" and "metrics".
+type Injectables map[string][]reflect.Type
+
+// candidates returns indexes of the types a parameter of type p can be injected from.
+// A type equal to p is used if present, otherwise all types implementing
+// p are candidates if p is an interface. Empty interfaces are never injected,
+// such parameters are always passed from JavaScript.
+func candidates(p reflect.Type, types []reflect.Type) []int {
+	if p.Kind() == reflect.Interface && p.NumMethod() == 0 {
+		return nil
+	}
+
+	for i, t := range types {
+		if t == p {
+			return []int{i}
+		}
+	}
+
+	if p.Kind() != reflect.Interface {
+		return nil
+	}
+
+	res := []int{}
+	for i, t := range types {
+		if t.Implements(p) {
+			res = append(res, i)
+		}
+	}
+
+	return res
+}
+
+// plan binds leading parameters of a function of type t to types.
+// It returns indexes of the types bound to the parameters and the index of the first
+// parameter that can't be injected, which is t.NumIn() when all parameters are bound.
+// Parameters matching more than one type are reported as ambiguous.
+func plan(t reflect.Type, types []reflect.Type) ([]int, int, error) {
+	bound := []int{}
+	for i := 0; i < t.NumIn(); i++ {
+		p := t.In(i)
+		c := candidates(p, types)
+		switch len(c) {
+		case 0:
+			return bound, i, nil
+		case 1:
+			bound = append(bound, c[0])
+		default:
+			matching := []string{}
+			for _, ci := range c {
+				matching = append(matching, types[ci].String())
+			}
+			return nil, i, fmt.Errorf("parameter %d (%s) is ambiguous, it matches %s", i+1, p, strings.Join(matching, ", "))
+		}
+	}
+	return bound, t.NumIn(), nil
+}
+
+// injectableIn returns contexts a parameter of type p can be injected in.
+func (in Injectables) injectableIn(p reflect.Type) []string {
+	contexts := []string{}
+	for c, types := range in {
+		if len(candidates(p, types)) > 0 {
+			contexts = append(contexts, c)
+		}
+	}
+	sort.Strings(contexts)
+	return contexts
+}
+
+// usable reports whether a global of type t can be used in the context.
+func (in Injectables) usable(t reflect.Type, context string) (bool, error) {
+	_, next, err := plan(t, in[context])
+	if err != nil {
+		return false, fmt.Errorf("%s context: %w", context, err)
+	}
+
+	if next == t.NumIn() {
+		return true, nil
+	}
+
+	// remaining parameters of functions are passed from JavaScript,
+	// unless they are meant to be injected in another context
+	if !isProvider(t) && len(in.injectableIn(t.In(next))) == 0 {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// ForContext returns the globals that can be used in the context. Globals with parameters
+// that can only be injected in other contexts, like the request in cron jobs, are left out.
+// Ambiguous parameters, provider parameters that can't be injected in any context and
+// globals that can't be used in any context are reported as errors.
+func (g Globals) ForContext(in Injectables, context string) (Globals, error) {
+	_, known := in[context]
+	if !known {
+		return nil, fmt.Errorf("unknown context %s", context)
+	}
+
+	names := make([]string, 0, len(g))
+	for k := range g {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	res := Globals{}
+	for _, name := range names {
+		v := g[name]
+		t := reflect.TypeOf(v)
+		if t == nil || t.Kind() != reflect.Func {
+			res[name] = v
+			continue
+		}
+
+		usable, err := in.usable(t, context)
+		if err != nil {
+			return nil, fmt.Errorf("global %s: %w", name, err)
+		}
+
+		if usable {
+			res[name] = v
+			continue
+		}
+
+		err = in.checkUsableSomewhere(t)
+		if err != nil {
+			return nil, fmt.Errorf("global %s: %w", name, err)
+		}
+	}
+
+	return res, nil
+}
+
+func (in Injectables) checkUsableSomewhere(t reflect.Type) error {
+	contexts := make([]string, 0, len(in))
+	for c := range in {
+		contexts = append(contexts, c)
+	}
+	sort.Strings(contexts)
+
+	for _, c := range contexts {
+		usable, err := in.usable(t, c)
+		if err != nil {
+			return err
+		}
+		if usable {
+			return nil
+		}
+	}
+
+	for i := 0; i < t.NumIn(); i++ {
+		p := t.In(i)
+		if len(in.injectableIn(p)) == 0 {
+			return fmt.Errorf("parameter %d (%s) can't be injected in any context", i+1, p)
+		}
+	}
+
+	return fmt.Errorf("parameters can't be injected in any single context")
+}
+
+// Injected returns the number of leading parameters of the function v that are
+// injected in the context. The remaining parameters are passed from JavaScript.
+func (in Injectables) Injected(v any, context string) int {
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Func {
+		return 0
+	}
+
+	_, next, err := plan(t, in[context])
+	if err != nil {
+		return 0
+	}
+	return next
+}
+
+// UsableIn returns the contexts the global v can be used in.
+func (in Injectables) UsableIn(v any) ([]string, error) {
+	contexts := make([]string, 0, len(in))
+	for c := range in {
+		contexts = append(contexts, c)
+	}
+	sort.Strings(contexts)
+
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Func {
+		return contexts, nil
+	}
+
+	res := []string{}
+	for _, c := range contexts {
+		usable, err := in.usable(t, c)
+		if err != nil {
+			return nil, err
+		}
+		if usable {
+			res = append(res, c)
+		}
+	}
+
+	return res, nil
+}
+
+// IsProvider reports whether v is a provider, a function returning Values.
+func IsProvider(v any) bool {
+	t := reflect.TypeOf(v)
+	return t != nil && t.Kind() == reflect.Func && isProvider(t)
+}
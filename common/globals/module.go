@@ -0,0 +1,40 @@
+package globals
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Module is a documented global. Its documentation ends up in the
+// TypeScript declarations generated for script authors.
+type Module struct {
+	// Name of the global.
+	Name string
+	// Doc describes the global.
+	Doc string
+	// Value is the global, a plain value, a function or a provider.
+	Value any
+	// Type describes the value returned by a provider, which is only known at
+	// run time, e.g. globals.TypeOf[*DB](). Defaults to the type of Value.
+	Type reflect.Type
+	// Members documents fields and methods of the global by their JavaScript names.
+	Members map[string]string
+	// Declaration replaces the generated TypeScript type of the global.
+	Declaration string
+}
+
+// Modules returns the globals of the modules.
+func Modules(mods ...Module) (Globals, error) {
+	res := Globals{}
+	for _, m := range mods {
+		if m.Name == "" {
+			return nil, fmt.Errorf("module without a name")
+		}
+		_, found := res[m.Name]
+		if found {
+			return nil, fmt.Errorf("module %s is registered twice", m.Name)
+		}
+		res[m.Name] = m.Value
+	}
+	return res, nil
+}
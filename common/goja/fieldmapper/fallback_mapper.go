@@ -3,6 +3,7 @@ package fieldmapper
 import (
 	"reflect"
 	"strings"
+	"unicode"
 
 	"github.com/dop251/goja/parser"
 )
@@ -10,6 +11,9 @@ import (
 const tagName string = "lean"
 
 type FallbackFieldMapper struct {
+	// CamelCase exposes methods and untagged fields with lowerCamel names,
+	// e.g. `Write` as `write` and `URL` as `url`. Names in `lean` tags are used as they are.
+	CamelCase bool
 }
 
 func (ffm FallbackFieldMapper) FieldName(_ reflect.Type, f reflect.StructField) string {
@@ -20,9 +24,43 @@ func (ffm FallbackFieldMapper) FieldName(_ reflect.Type, f reflect.StructField)
 	if parser.IsIdentifier(tag) {
 		return tag
 	}
+	if ffm.CamelCase {
+		return LowerCamel(f.Name)
+	}
 	return f.Name
 }
 
 func (ffm FallbackFieldMapper) MethodName(_ reflect.Type, m reflect.Method) string {
+	if ffm.CamelCase {
+		return LowerCamel(m.Name)
+	}
 	return m.Name
 }
+
+// LowerCamel converts an exported Go name to lowerCamel case.
+// Leading initialisms are lower cased as a whole: `URL` becomes `url`,
+// `HTTPServer` becomes `httpServer` and `ServeHTTP` becomes `serveHTTP`.
+func LowerCamel(name string) string {
+	runes := []rune(name)
+
+	upper := 0
+	for upper < len(runes) && unicode.IsUpper(runes[upper]) {
+		upper++
+	}
+
+	switch {
+	case upper == 0:
+		return name
+	case upper == len(runes):
+		return strings.ToLower(name)
+	case upper > 1 && unicode.IsLower(runes[upper]):
+		// last upper case rune starts the next word
+		upper--
+	}
+
+	for i := 0; i < upper; i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+
+	return string(runes)
+}
@@ -0,0 +1,46 @@
+package fieldmapper
+
+import (
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLowerCamel(t *testing.T) {
+	for name, expected := range map[string]string{
+		"Write":          "write",
+		"URL":            "url",
+		"ID":             "id",
+		"URLPath":        "urlPath",
+		"HTTPServer":     "httpServer",
+		"ServeHTTP":      "serveHTTP",
+		"ContentLength":  "contentLength",
+		"V2":             "v2",
+		"alreadyCamel":   "alreadyCamel",
+		"XMLHttpRequest": "xmlHttpRequest",
+	} {
+		require.Equal(t, expected, LowerCamel(name), name)
+	}
+}
+
+type camelCaseTest struct {
+	RemoteAddr string
+	Tagged     string `lean:"Tagged_As_Is"`
+}
+
+func (camelCaseTest) GetURL() string {
+	return "url"
+}
+
+func TestCamelCaseMapping(t *testing.T) {
+	require := require.New(t)
+
+	rt := goja.New()
+	rt.SetFieldNameMapper(FallbackFieldMapper{CamelCase: true})
+	rt.Set("v", camelCaseTest{RemoteAddr: "addr", Tagged: "tagged"})
+
+	res, err := rt.RunString("[v.remoteAddr, v.Tagged_As_Is, v.getURL(), v.RemoteAddr === undefined].join()")
+	require.NoError(err)
+	require.Equal("addr,tagged,url,true", res.String())
+}
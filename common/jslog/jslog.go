@@ -0,0 +1,283 @@
+package jslog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/draganm/go-lean/common/globals"
+	"github.com/draganm/go-lean/common/jsstack"
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Level is the minimum level of messages logged from JavaScript.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// ParseLevel parses one of `debug`, `info`, `warn` or `error`.
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	default:
+		return LevelDebug, fmt.Errorf("unknown log level %q", s)
+	}
+}
+
+// objects nested deeper than this are logged as they are
+const maxFlattenDepth = 5
+
+// Provider returns a provider of the `log` global. It logs to the logger found in the context,
+// which carries the handler path or the cron job name, adding trace and span IDs
+// of the current span. Messages below minLevel are discarded.
+//
+//	log.info("user created", {user: {id: 1}})  // "user.id"=1
+//	log.warn("slow query", "duration", 1200)
+//	log.error("could not create user", err, {userId: 1})
+func Provider(minLevel Level) func(ctx context.Context, rt *goja.Runtime) globals.Values {
+	return func(ctx context.Context, rt *goja.Runtime) globals.Values {
+		l := &logger{
+			rt:       rt,
+			log:      logr.FromContextOrDiscard(ctx),
+			minLevel: minLevel,
+		}
+
+		sc := trace.SpanContextFromContext(ctx)
+		if sc.IsValid() {
+			l.log = l.log.WithValues("traceID", sc.TraceID().String(), "spanID", sc.SpanID().String())
+		}
+
+		return l.values()
+	}
+}
+
+type logger struct {
+	rt       *goja.Runtime
+	log      logr.Logger
+	minLevel Level
+}
+
+// values returns the methods of the global. V, WithValues and WithName return
+// a derived logger with the same methods, as logr.Logger does.
+func (l *logger) values() map[string]any {
+	return map[string]any{
+		"debug": l.debug,
+		"info":  l.info,
+		"warn":  l.warn,
+		"error": l.error,
+
+		// logr style, kept for compatibility with handlers
+		// that have been written against logr.Logger
+		"Info":       l.info,
+		"Error":      l.logrError,
+		"V":          l.v,
+		"WithValues": l.withValues,
+		"WithName":   l.withName,
+		"Enabled":    l.log.Enabled,
+	}
+}
+
+func (l *logger) with(log logr.Logger) map[string]any {
+	derived := *l
+	derived.log = log
+	return derived.values()
+}
+
+func (l *logger) v(level int) map[string]any {
+	return l.with(l.log.V(level))
+}
+
+func (l *logger) withValues(call goja.FunctionCall) goja.Value {
+	kv, _ := l.keyValues(call.Arguments, false)
+	return l.rt.ToValue(l.with(l.log.WithValues(kv...)))
+}
+
+func (l *logger) withName(name string) map[string]any {
+	return l.with(l.log.WithName(name))
+}
+
+func (l *logger) debug(call goja.FunctionCall) goja.Value {
+	if l.minLevel <= LevelDebug {
+		msg, kv, _ := l.args(call.Arguments, false)
+		l.log.V(1).Info(msg, kv...)
+	}
+	return goja.Undefined()
+}
+
+func (l *logger) info(call goja.FunctionCall) goja.Value {
+	if l.minLevel <= LevelInfo {
+		msg, kv, _ := l.args(call.Arguments, false)
+		l.log.Info(msg, kv...)
+	}
+	return goja.Undefined()
+}
+
+func (l *logger) warn(call goja.FunctionCall) goja.Value {
+	if l.minLevel <= LevelWarn {
+		msg, kv, _ := l.args(call.Arguments, false)
+		l.log.Info(msg, append([]any{"level", "warn"}, kv...)...)
+	}
+	return goja.Undefined()
+}
+
+func (l *logger) error(call goja.FunctionCall) goja.Value {
+	if l.minLevel <= LevelError {
+		msg, kv, err := l.args(call.Arguments, true)
+		l.log.Error(err, msg, kv...)
+	}
+	return goja.Undefined()
+}
+
+func (l *logger) logrError(call goja.FunctionCall) goja.Value {
+	if l.minLevel <= LevelError {
+		err := l.toError(call.Argument(0))
+		rest := []goja.Value{}
+		if len(call.Arguments) > 1 {
+			rest = call.Arguments[1:]
+		}
+		msg, kv, _ := l.args(rest, false)
+		l.log.Error(err, msg, kv...)
+	}
+	return goja.Undefined()
+}
+
+// args converts JavaScript arguments into message and key/value pairs.
+// Objects are flattened into key/value pairs using dotted keys,
+// other arguments are treated as key/value pairs.
+func (l *logger) args(args []goja.Value, extractError bool) (string, []any, error) {
+	if len(args) == 0 {
+		return "", nil, nil
+	}
+
+	kv, err := l.keyValues(args[1:], extractError)
+	return args[0].String(), kv, err
+}
+
+// keyValues converts the arguments following the message into key/value pairs,
+// returning the first Error if extractError is set.
+func (l *logger) keyValues(rest []goja.Value, extractError bool) ([]any, error) {
+	kv := []any{}
+	var err error
+
+	for i := 0; i < len(rest); i++ {
+		a := rest[i]
+
+		if extractError && err == nil && l.isError(a) {
+			err = l.toError(a)
+			continue
+		}
+
+		obj, isObject := a.(*goja.Object)
+		if isObject && l.isPlainObject(obj) {
+			kv = l.flatten(kv, "", obj, 0)
+			continue
+		}
+
+		if i+1 < len(rest) {
+			kv = append(kv, a.String(), export(rest[i+1]))
+			i++
+			continue
+		}
+
+		kv = append(kv, "arg", export(a))
+	}
+
+	return kv, err
+}
+
+func (l *logger) flatten(kv []any, prefix string, obj *goja.Object, depth int) []any {
+	for _, k := range obj.Keys() {
+		v := obj.Get(k)
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+
+		nested, isObject := v.(*goja.Object)
+		if isObject && depth < maxFlattenDepth && l.isPlainObject(nested) {
+			kv = l.flatten(kv, key, nested, depth+1)
+			continue
+		}
+
+		if isObject && l.isError(nested) {
+			kv = append(kv, key, l.toError(nested).Error())
+			continue
+		}
+
+		kv = append(kv, key, export(v))
+	}
+	return kv
+}
+
+func export(v goja.Value) any {
+	if v == nil {
+		return nil
+	}
+	return v.Export()
+}
+
+func (l *logger) isPlainObject(obj *goja.Object) bool {
+	if obj.ClassName() != "Object" {
+		return false
+	}
+	proto := obj.Prototype()
+	return proto == nil || proto.Prototype() == nil
+}
+
+func (l *logger) isError(v goja.Value) bool {
+	obj, isObject := v.(*goja.Object)
+	if !isObject {
+		return false
+	}
+	errorConstructor, isObject := l.rt.Get("Error").(*goja.Object)
+	if !isObject {
+		return false
+	}
+	return l.rt.InstanceOf(obj, errorConstructor)
+}
+
+// jsError is a JavaScript Error passed to the logger
+type jsError struct {
+	message string
+	stack   []jsstack.Frame
+}
+
+func (e *jsError) Error() string {
+	if len(e.stack) == 0 {
+		return e.message
+	}
+	return fmt.Sprintf("%s at %s", e.message, e.stack[0])
+}
+
+func (l *logger) toError(v goja.Value) error {
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return nil
+	}
+
+	obj, isObject := v.(*goja.Object)
+	if !isObject || !l.isError(obj) {
+		return errors.New(v.String())
+	}
+
+	e := &jsError{message: obj.String()}
+	st := obj.Get("stack")
+	if st != nil && !goja.IsUndefined(st) {
+		e.stack = jsstack.Parse(st.String())
+	}
+	return e
+}
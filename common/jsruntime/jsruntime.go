@@ -0,0 +1,110 @@
+package jsruntime
+
+import (
+	"fmt"
+
+	"github.com/dop251/goja"
+	"github.com/draganm/go-lean/common/eventloop"
+	"github.com/draganm/go-lean/common/globals"
+	"github.com/draganm/go-lean/common/goja/fieldmapper"
+	"github.com/draganm/go-lean/common/limits"
+)
+
+// Factory creates the runtimes of web handlers, cron jobs and metrics,
+// so that scripts see the same field names, built-ins and globals in every context.
+type Factory struct {
+	// FieldMapper names Go fields and methods in JavaScript,
+	// defaults to fieldmapper.FallbackFieldMapper.
+	FieldMapper goja.FieldNameMapper
+
+	// Init is called for every created runtime, before the globals are set.
+	Init []func(*goja.Runtime) error
+
+	// Globals of all contexts, e.g. `require`, `log` and the user globals.
+	// Each context only gets the ones that can be injected there.
+	Globals globals.Globals
+
+	// Contexts are globals only set in one context, keyed by the context. The same name
+	// can be set in several contexts, e.g. template globals rendering to the response in handlers.
+	Contexts map[string]globals.Globals
+
+	// Injectables are the types injected into providers per context.
+	Injectables globals.Injectables
+
+	// Limits are applied to every runtime.
+	Limits limits.Limits
+}
+
+// Environment returns the environment of scripts running in the context.
+// Extras are globals only set in that context, e.g. `returnStatus` in handlers.
+func (f *Factory) Environment(context string, extras globals.Globals) (*Environment, error) {
+	gl, err := f.Globals.Merge(f.Contexts[context])
+	if err != nil {
+		return nil, fmt.Errorf("could not merge %s globals: %w", context, err)
+	}
+
+	gl, err = gl.Merge(extras)
+	if err != nil {
+		return nil, fmt.Errorf("could not merge %s globals: %w", context, err)
+	}
+
+	gl, err = gl.ForContext(f.Injectables, context)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s globals: %w", context, err)
+	}
+
+	return &Environment{
+		Globals: gl,
+		Limits:  f.Limits,
+		factory: f,
+	}, nil
+}
+
+// Environment creates runtimes for scripts of one context.
+type Environment struct {
+	// Globals that can be used in the context. They are not set by New,
+	// since providers are wired for every invocation.
+	Globals globals.Globals
+
+	// Limits to enforce for every invocation.
+	Limits limits.Limits
+
+	factory *Factory
+}
+
+// New creates a runtime with the field mapper, limits and initializers
+// of the factory applied, and an event loop providing timers.
+func (e *Environment) New() (*goja.Runtime, *eventloop.EventLoop, error) {
+	return e.factory.New()
+}
+
+// New creates a runtime without globals, e.g. for template helpers
+// that don't run in any context. See Environment.New.
+func (f *Factory) New() (*goja.Runtime, *eventloop.EventLoop, error) {
+	rt := goja.New()
+
+	var mapper goja.FieldNameMapper = fieldmapper.FallbackFieldMapper{}
+	if f.FieldMapper != nil {
+		mapper = f.FieldMapper
+	}
+	rt.SetFieldNameMapper(mapper)
+
+	err := f.Limits.InitRuntime(rt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not apply limits: %w", err)
+	}
+
+	for _, initRuntime := range f.Init {
+		err = initRuntime(rt)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not initialize runtime: %w", err)
+		}
+	}
+
+	loop, err := eventloop.New(rt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create event loop: %w", err)
+	}
+
+	return rt, loop, nil
+}
@@ -0,0 +1,92 @@
+package jsstack
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+// Frame is a single frame of a JavaScript stack trace.
+// When the script has been transpiled, the position
+// is already mapped to the original source.
+type Frame struct {
+	Function string `json:"function,omitempty"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+}
+
+func (f Frame) String() string {
+	if f.Function == "" {
+		return fmt.Sprintf("%s:%d:%d", f.File, f.Line, f.Column)
+	}
+	return fmt.Sprintf("%s (%s:%d:%d)", f.Function, f.File, f.Line, f.Column)
+}
+
+// goja writes frames as `at fn (file:line:column(pc))` or `at file:line:column(pc)`
+var frameRegexp = regexp.MustCompile(`^\s*at (?:(.+?) \()?(.+):(\d+):(\d+)\(\d+\)\)?$`)
+
+// Parse parses the stack of a JavaScript Error object.
+func Parse(stack string) []Frame {
+	frames := []Frame{}
+	for _, line := range strings.Split(stack, "\n") {
+		m := frameRegexp.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		ln, _ := strconv.Atoi(m[3])
+		col, _ := strconv.Atoi(m[4])
+
+		frames = append(frames, Frame{
+			Function: m[1],
+			File:     m[2],
+			Line:     ln,
+			Column:   col,
+		})
+	}
+	return frames
+}
+
+// FromError returns the JavaScript stack of an error returned by goja.
+// If a JavaScript Error object has been thrown, its stack is used, since
+// it points to the place where the error was created, even if it has
+// been re-thrown when a promise was rejected.
+func FromError(err error) []Frame {
+	exc := &goja.Exception{}
+	if errors.As(err, &exc) {
+		obj, isObject := exc.Value().(*goja.Object)
+		if isObject {
+			st := obj.Get("stack")
+			if st != nil && !goja.IsUndefined(st) && !goja.IsNull(st) {
+				frames := Parse(st.String())
+				if len(frames) > 0 {
+					return frames
+				}
+			}
+		}
+		return Parse(exc.String())
+	}
+
+	ie := &goja.InterruptedError{}
+	if errors.As(err, &ie) {
+		return Parse(ie.String())
+	}
+
+	return nil
+}
+
+// Format returns the stack in the same format goja uses.
+func Format(frames []Frame) string {
+	sb := &strings.Builder{}
+	for _, f := range frames {
+		sb.WriteString("\tat ")
+		sb.WriteString(f.String())
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
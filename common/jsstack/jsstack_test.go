@@ -0,0 +1,25 @@
+package jsstack
+
+import (
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromError(t *testing.T) {
+	require := require.New(t)
+
+	rt := goja.New()
+	_, err := rt.RunScript("/lib/fail.js", "function fail() {\n  throw new Error('boom')\n}\nfail()\n")
+	require.Error(err)
+
+	require.Equal([]Frame{
+		{Function: "fail", File: "/lib/fail.js", Line: 2, Column: 9},
+		{File: "/lib/fail.js", Line: 4, Column: 5},
+	}, FromError(err))
+}
+
+func TestFromErrorOfNonJSError(t *testing.T) {
+	require.Nil(t, FromError(nil))
+}
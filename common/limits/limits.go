@@ -0,0 +1,155 @@
+package limits
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	ErrCallStackExceeded  = errors.New("call stack size exceeded")
+	ErrTimeBudgetExceeded = errors.New("time budget exceeded")
+	ErrResponseTooLarge   = errors.New("response size limit exceeded")
+)
+
+var violations = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "lean_resource_limit_violations_count",
+	Help: "Number of times a script has exceeded a resource limit",
+}, []string{"limit", "script"})
+
+// Limits of JavaScript runtimes. Zero values mean no limit.
+type Limits struct {
+	// MaxCallStackSize is the maximum function call depth.
+	MaxCallStackSize int
+	// TimeBudget is the maximum duration of a single invocation of a handler,
+	// cron job or metric collection, including waiting for timers and promises.
+	TimeBudget time.Duration
+	// MaxResponseBytes is the maximum number of bytes a handler can write
+	// to the response body.
+	MaxResponseBytes int64
+}
+
+// InitRuntime applies limits that are set once per runtime.
+func (l Limits) InitRuntime(rt *goja.Runtime) error {
+	if l.MaxCallStackSize > 0 {
+		rt.SetMaxCallStackSize(l.MaxCallStackSize)
+	}
+	return nil
+}
+
+// Budget enforces the time budget of a single invocation.
+type Budget struct {
+	rt       *goja.Runtime
+	cancel   context.CancelCauseFunc
+	timer    *time.Timer
+	mu       *sync.Mutex
+	stopped  bool
+	exceeded bool
+}
+
+// StartBudget starts the time budget of an invocation running on rt.
+// When the budget is exhausted, the runtime is interrupted and the returned
+// context is cancelled, which stops waiting for pending timers and promises.
+// Stop has to be called once the invocation is done.
+func (l Limits) StartBudget(ctx context.Context, rt *goja.Runtime) (context.Context, *Budget) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	b := &Budget{
+		rt:     rt,
+		cancel: cancel,
+		mu:     &sync.Mutex{},
+	}
+
+	if l.TimeBudget > 0 {
+		b.timer = time.AfterFunc(l.TimeBudget, func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if b.stopped {
+				return
+			}
+			b.exceeded = true
+			rt.Interrupt(ErrTimeBudgetExceeded)
+			cancel(ErrTimeBudgetExceeded)
+		})
+	}
+
+	return ctx, b
+}
+
+// Stop stops the budget and clears the interrupt it may have caused.
+// If the invocation has failed because the budget has been exhausted,
+// the returned error wraps both ErrTimeBudgetExceeded and err.
+func (b *Budget) Stop(err error) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.stopped = true
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	b.cancel(nil)
+
+	if !b.exceeded {
+		return err
+	}
+
+	b.rt.ClearInterrupt()
+
+	if err == nil || errors.Is(err, ErrTimeBudgetExceeded) {
+		return err
+	}
+
+	return fmt.Errorf("%w: %w", ErrTimeBudgetExceeded, err)
+}
+
+// Violation returns the limit that has been violated if err
+// has been caused by a limit violation, nil otherwise.
+func Violation(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrTimeBudgetExceeded):
+		return ErrTimeBudgetExceeded
+	case errors.Is(err, ErrResponseTooLarge):
+		return ErrResponseTooLarge
+	case errors.Is(err, ErrCallStackExceeded):
+		return ErrCallStackExceeded
+	}
+
+	so := &goja.StackOverflowError{}
+	if errors.As(err, &so) {
+		return ErrCallStackExceeded
+	}
+
+	return nil
+}
+
+// Report counts the violation in metrics if err has been caused by
+// a limit violation and returns the violated limit.
+func Report(script string, err error) error {
+	v := Violation(err)
+	if v == nil {
+		return nil
+	}
+
+	violations.WithLabelValues(limitName(v), script).Inc()
+	return v
+}
+
+func limitName(v error) string {
+	switch v {
+	case ErrCallStackExceeded:
+		return "call_stack"
+	case ErrTimeBudgetExceeded:
+		return "time_budget"
+	case ErrResponseTooLarge:
+		return "response_size"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,57 @@
+package limits
+
+import (
+	"net/http"
+)
+
+// ResponseWriter caps the number of bytes written to the response body.
+// A write that would exceed the cap is rejected as a whole with ErrResponseTooLarge.
+type ResponseWriter struct {
+	http.ResponseWriter
+	remaining     int64
+	exceeded      bool
+	headerWritten bool
+}
+
+// LimitResponse returns w unchanged when MaxResponseBytes is not set.
+func (l Limits) LimitResponse(w http.ResponseWriter) http.ResponseWriter {
+	if l.MaxResponseBytes <= 0 {
+		return w
+	}
+	return &ResponseWriter{
+		ResponseWriter: w,
+		remaining:      l.MaxResponseBytes,
+	}
+}
+
+func (w *ResponseWriter) WriteHeader(statusCode int) {
+	w.headerWritten = true
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *ResponseWriter) Write(data []byte) (int, error) {
+	if int64(len(data)) > w.remaining {
+		w.exceeded = true
+		return 0, ErrResponseTooLarge
+	}
+	w.headerWritten = true
+	n, err := w.ResponseWriter.Write(data)
+	w.remaining -= int64(n)
+	return n, err
+}
+
+// Unwrap is used by http.ResponseController.
+func (w *ResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Exceeded reports whether a write has been rejected.
+// The error may have been caught by the script, so it is tracked here as well.
+func (w *ResponseWriter) Exceeded() bool {
+	return w.exceeded
+}
+
+// HeaderWritten reports whether the status has already been sent.
+func (w *ResponseWriter) HeaderWritten() bool {
+	return w.headerWritten
+}
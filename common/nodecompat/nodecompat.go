@@ -0,0 +1,203 @@
+package nodecompat
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja_nodejs/buffer"
+	"github.com/dop251/goja_nodejs/url"
+	"github.com/dop251/goja_nodejs/util"
+	"github.com/draganm/go-lean/common/globals"
+	"github.com/go-logr/logr"
+)
+
+// Options of the Node.js compatibility layer.
+type Options struct {
+	// EnvAllowlist contains names of environment variables
+	// exposed through `process.env`. No variables are exposed by default.
+	EnvAllowlist []string
+}
+
+// Globals returns globals that have to be wired per invocation,
+// `console` logs to the logger found in the context.
+func Globals() globals.Globals {
+	return globals.Globals{
+		"console": consoleProvider,
+	}
+}
+
+// Enable installs `Buffer`, `URL`, `URLSearchParams`, `TextEncoder`,
+// `TextDecoder` and `process` into the runtime.
+func Enable(rt *goja.Runtime, opts Options) error {
+	global := rt.GlobalObject()
+
+	for name, load := range map[string]func(*goja.Runtime, *goja.Object){
+		"buffer": buffer.Require,
+		"url":    url.Require,
+	} {
+		exports, err := loadModule(rt, load)
+		if err != nil {
+			return fmt.Errorf("could not load %s: %w", name, err)
+		}
+
+		for _, k := range exports.Keys() {
+			err = global.Set(k, exports.Get(k))
+			if err != nil {
+				return fmt.Errorf("could not set %s: %w", k, err)
+			}
+		}
+	}
+
+	err := enableTextEncoding(rt)
+	if err != nil {
+		return err
+	}
+
+	env := map[string]string{}
+	for _, name := range opts.EnvAllowlist {
+		v, found := os.LookupEnv(name)
+		if found {
+			env[name] = v
+		}
+	}
+
+	process := rt.NewObject()
+	err = process.Set("env", env)
+	if err != nil {
+		return fmt.Errorf("could not set process.env: %w", err)
+	}
+
+	err = global.Set("process", process)
+	if err != nil {
+		return fmt.Errorf("could not set process: %w", err)
+	}
+
+	return nil
+}
+
+func loadModule(rt *goja.Runtime, load func(*goja.Runtime, *goja.Object)) (exports *goja.Object, err error) {
+	defer func() {
+		p := recover()
+		if p != nil {
+			err = fmt.Errorf("%v", p)
+		}
+	}()
+
+	module := rt.NewObject()
+	exports = rt.NewObject()
+	err = module.Set("exports", exports)
+	if err != nil {
+		return nil, err
+	}
+
+	load(rt, module)
+
+	return exports, nil
+}
+
+const textEncodingShim = `(function(encode, decode) {
+	class TextEncoder {
+		get encoding() { return "utf-8" }
+		encode(input = "") { return encode(String(input)) }
+	}
+
+	class TextDecoder {
+		constructor(label = "utf-8") {
+			if (!/^(unicode-1-1-)?utf-?8$/i.test(label)) {
+				throw new RangeError("The encoding " + label + " is not supported")
+			}
+		}
+		get encoding() { return "utf-8" }
+		decode(input) { return input === undefined ? "" : decode(input) }
+	}
+
+	return { TextEncoder, TextDecoder }
+})`
+
+func enableTextEncoding(rt *goja.Runtime) error {
+	shim, err := rt.RunString(textEncodingShim)
+	if err != nil {
+		return fmt.Errorf("could not evaluate text encoding shim: %w", err)
+	}
+
+	create, isFunction := goja.AssertFunction(shim)
+	if !isFunction {
+		return fmt.Errorf("text encoding shim is not a function")
+	}
+
+	uint8Array, isConstructor := goja.AssertConstructor(rt.Get("Uint8Array"))
+	if !isConstructor {
+		return fmt.Errorf("Uint8Array is not a constructor")
+	}
+
+	encode := func(s string) (*goja.Object, error) {
+		return uint8Array(nil, rt.ToValue(rt.NewArrayBuffer([]byte(s))))
+	}
+
+	decode := func(v goja.Value) (string, error) {
+		ab, isArrayBuffer := v.Export().(goja.ArrayBuffer)
+		if isArrayBuffer {
+			return string(ab.Bytes()), nil
+		}
+
+		var b []byte
+		err := rt.ExportTo(v, &b)
+		if err != nil {
+			return "", fmt.Errorf("input must be an ArrayBuffer or ArrayBufferView: %w", err)
+		}
+		return string(b), nil
+	}
+
+	classes, err := create(nil, rt.ToValue(encode), rt.ToValue(decode))
+	if err != nil {
+		return fmt.Errorf("could not create text encoding classes: %w", err)
+	}
+
+	classesObject := classes.ToObject(rt)
+	for _, k := range []string{"TextEncoder", "TextDecoder"} {
+		err = rt.GlobalObject().Set(k, classesObject.Get(k))
+		if err != nil {
+			return fmt.Errorf("could not set %s: %w", k, err)
+		}
+	}
+
+	return nil
+}
+
+func consoleProvider(ctx context.Context, rt *goja.Runtime) globals.Values {
+	log := logr.FromContextOrDiscard(ctx).WithName("console")
+	u := util.New(rt)
+
+	format := func(call goja.FunctionCall) string {
+		b := &bytes.Buffer{}
+		if len(call.Arguments) > 0 {
+			u.Format(b, call.Arguments[0].String(), call.Arguments[1:]...)
+		}
+		return b.String()
+	}
+
+	info := func(call goja.FunctionCall) goja.Value {
+		log.Info(format(call))
+		return goja.Undefined()
+	}
+
+	return globals.Values{
+		"log":  info,
+		"info": info,
+		"debug": func(call goja.FunctionCall) goja.Value {
+			log.V(1).Info(format(call))
+			return goja.Undefined()
+		},
+		"warn": func(call goja.FunctionCall) goja.Value {
+			log.Info(format(call), "level", "warn")
+			return goja.Undefined()
+		},
+		"error": func(call goja.FunctionCall) goja.Value {
+			log.Error(nil, format(call))
+			return goja.Undefined()
+		},
+	}
+}
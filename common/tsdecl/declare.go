@@ -0,0 +1,54 @@
+package tsdecl
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/draganm/go-lean/common/globals"
+)
+
+// Declare returns the declaration of the global described by the module.
+// The first injected parameters of functions are not visible to JavaScript.
+func (g *Generator) Declare(m globals.Module, injected int) string {
+	doc := Comment(m.Doc, "")
+
+	if m.Declaration != "" {
+		return fmt.Sprintf("%sdeclare const %s: %s\n", doc, m.Name, strings.TrimSpace(m.Declaration))
+	}
+
+	t := m.Type
+	if t == nil && !globals.IsProvider(m.Value) {
+		t = reflect.TypeOf(m.Value)
+	}
+
+	if t == nil {
+		return fmt.Sprintf("%sdeclare const %s: any\n", doc, m.Name)
+	}
+
+	if t.Kind() == reflect.Func && m.Type == nil {
+		return fmt.Sprintf("%sdeclare function %s%s\n", doc, m.Name, g.Function(t, injected))
+	}
+
+	if isObject(t) {
+		return fmt.Sprintf("%sdeclare const %s: {\n%s}\n", doc, m.Name, g.Members(t, m.Members, "  "))
+	}
+
+	return fmt.Sprintf("%sdeclare const %s: %s\n", doc, m.Name, g.Type(t))
+}
+
+// isObject reports whether values of t are exposed as objects with members.
+func isObject(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return true
+	case reflect.Interface:
+		return t.NumMethod() > 0
+	default:
+		return false
+	}
+}
@@ -0,0 +1,275 @@
+package tsdecl
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/dop251/goja"
+)
+
+// types nested deeper than this are declared as `any`,
+// otherwise e.g. *http.Request would pull in most of the standard library
+const maxDepth = 3
+
+var (
+	errorType        = reflect.TypeOf((*error)(nil)).Elem()
+	valueType        = reflect.TypeOf((*goja.Value)(nil)).Elem()
+	objectType       = reflect.TypeOf(&goja.Object{})
+	promiseType      = reflect.TypeOf(&goja.Promise{})
+	callableType     = reflect.TypeOf(goja.Callable(nil))
+	functionCallType = reflect.TypeOf(goja.FunctionCall{})
+	arrayBufferType  = reflect.TypeOf(goja.ArrayBuffer{})
+	bytesType        = reflect.TypeOf([]byte{})
+)
+
+// Generator converts Go types into TypeScript declarations,
+// naming fields and methods the same way the runtime does.
+type Generator struct {
+	mapper     goja.FieldNameMapper
+	names      map[reflect.Type]string
+	taken      map[string]bool
+	interfaces []string
+}
+
+func New(mapper goja.FieldNameMapper) *Generator {
+	return &Generator{
+		mapper: mapper,
+		names:  map[reflect.Type]string{},
+		taken:  map[string]bool{},
+	}
+}
+
+// Interfaces returns declarations of the named Go types referenced so far.
+func (g *Generator) Interfaces() string {
+	return strings.Join(g.interfaces, "\n")
+}
+
+// Comment formats doc as a JSDoc comment with the given indentation.
+func Comment(doc string, indent string) string {
+	doc = strings.TrimSpace(doc)
+	if doc == "" {
+		return ""
+	}
+
+	sb := &strings.Builder{}
+	sb.WriteString(indent + "/**\n")
+	for _, line := range strings.Split(doc, "\n") {
+		sb.WriteString(strings.TrimRight(indent+" * "+line, " ") + "\n")
+	}
+	sb.WriteString(indent + " */\n")
+	return sb.String()
+}
+
+// Type returns the TypeScript type of values of the Go type t.
+func (g *Generator) Type(t reflect.Type) string {
+	return g.typeExpr(t, 0)
+}
+
+// Function returns the TypeScript parameters and result of a function type,
+// e.g. `(arg1: string, arg2: number): boolean`. The first skip parameters
+// are injected and not visible to JavaScript.
+func (g *Generator) Function(t reflect.Type, skip int) string {
+	params, result := g.signature(t, skip, 0)
+	return "(" + params + "): " + result
+}
+
+// Members returns the members of an object type, one per line,
+// documented with docs keyed by their JavaScript names.
+func (g *Generator) Members(t reflect.Type, docs map[string]string, indent string) string {
+	return g.members(t, docs, indent, 0)
+}
+
+func (g *Generator) typeExpr(t reflect.Type, depth int) string {
+	switch t {
+	case errorType:
+		return "Error"
+	case valueType, objectType:
+		return "any"
+	case promiseType:
+		return "Promise<any>"
+	case callableType:
+		return "(...args: any[]) => any"
+	case arrayBufferType:
+		return "ArrayBuffer"
+	case bytesType:
+		return "string | number[]"
+	}
+
+	if t.Kind() == reflect.Func && t.NumIn() == 1 && t.In(0) == functionCallType {
+		return "(...args: any[]) => any"
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Pointer:
+		return g.typeExpr(t.Elem(), depth)
+	case reflect.Slice, reflect.Array:
+		return arrayOf(g.typeExpr(t.Elem(), depth))
+	case reflect.Map:
+		return fmt.Sprintf("Record<string, %s>", g.typeExpr(t.Elem(), depth))
+	case reflect.Func:
+		params, result := g.signature(t, 0, depth)
+		return "(" + params + ") => " + result
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			return "any"
+		}
+		return g.named(t, depth)
+	case reflect.Struct:
+		if t.Name() == "" {
+			return "{ " + strings.Join(strings.Split(strings.TrimSpace(g.members(t, nil, "", depth+1)), "\n"), "; ") + " }"
+		}
+		return g.named(t, depth)
+	default:
+		return "any"
+	}
+}
+
+func arrayOf(elem string) string {
+	if strings.ContainsAny(elem, " |") {
+		return "(" + elem + ")[]"
+	}
+	return elem + "[]"
+}
+
+// named declares an interface for a named Go type and returns its name.
+func (g *Generator) named(t reflect.Type, depth int) string {
+	name, found := g.names[t]
+	if found {
+		return name
+	}
+
+	if depth >= maxDepth || t.Name() == "" {
+		return "any"
+	}
+
+	name = t.Name()
+	if g.taken[name] {
+		name = exportedName(path(t)) + name
+	}
+	for i := 2; g.taken[name]; i++ {
+		name = fmt.Sprintf("%s%d", t.Name(), i)
+	}
+
+	g.names[t] = name
+	g.taken[name] = true
+
+	// reserve the position, members can reference other named types
+	idx := len(g.interfaces)
+	g.interfaces = append(g.interfaces, "")
+	g.interfaces[idx] = fmt.Sprintf("/** %s */\ninterface %s {\n%s}\n", t.String(), name, g.members(t, nil, "  ", depth+1))
+
+	return name
+}
+
+func path(t reflect.Type) string {
+	pkg := t.PkgPath()
+	idx := strings.LastIndexByte(pkg, '/')
+	return pkg[idx+1:]
+}
+
+func exportedName(s string) string {
+	if s == "" {
+		return s
+	}
+	runes := []rune(s)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+func (g *Generator) members(t reflect.Type, docs map[string]string, indent string, depth int) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	lines := []string{}
+	add := func(name, decl string) {
+		lines = append(lines, Comment(docs[name], indent)+indent+decl+"\n")
+	}
+
+	if t.Kind() == reflect.Struct {
+		for _, f := range reflect.VisibleFields(t) {
+			if !f.IsExported() || f.Anonymous {
+				continue
+			}
+			name := g.mapper.FieldName(t, f)
+			if name == "" {
+				continue
+			}
+			add(name, fmt.Sprintf("%s: %s", name, g.typeExpr(f.Type, depth)))
+		}
+	}
+
+	// methods of pointers are exposed as well, values are usually passed by pointer
+	mt := t
+	if t.Kind() != reflect.Interface {
+		mt = reflect.PointerTo(t)
+	}
+
+	skip := 1
+	if t.Kind() == reflect.Interface {
+		skip = 0
+	}
+
+	for i := 0; i < mt.NumMethod(); i++ {
+		m := mt.Method(i)
+		if !m.IsExported() {
+			continue
+		}
+		name := g.mapper.MethodName(t, m)
+		if name == "" {
+			continue
+		}
+		params, result := g.signature(m.Type, skip, depth)
+		add(name, name+"("+params+"): "+result)
+	}
+
+	return strings.Join(lines, "")
+}
+
+// signature returns the parameters and the result type of a function type.
+func (g *Generator) signature(t reflect.Type, skip int, depth int) (string, string) {
+	if t.NumIn() == 1 && t.In(0) == functionCallType {
+		return "...args: any[]", "any"
+	}
+
+	params := []string{}
+	for i := skip; i < t.NumIn(); i++ {
+		p := t.In(i)
+		name := fmt.Sprintf("arg%d", i-skip+1)
+		if t.IsVariadic() && i == t.NumIn()-1 {
+			params = append(params, fmt.Sprintf("...%s: %s", name, arrayOf(g.typeExpr(p.Elem(), depth))))
+			continue
+		}
+		params = append(params, fmt.Sprintf("%s: %s", name, g.typeExpr(p, depth)))
+	}
+
+	results := []string{}
+	for i := 0; i < t.NumOut(); i++ {
+		o := t.Out(i)
+		// errors are thrown
+		if o == errorType && i == t.NumOut()-1 {
+			continue
+		}
+		results = append(results, g.typeExpr(o, depth))
+	}
+
+	result := "void"
+	switch {
+	case len(results) == 1:
+		result = results[0]
+	case len(results) > 1:
+		result = "[" + strings.Join(results, ", ") + "]"
+	}
+
+	return strings.Join(params, ", "), result
+}
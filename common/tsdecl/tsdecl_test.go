@@ -0,0 +1,101 @@
+package tsdecl_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/draganm/go-lean/common/globals"
+	"github.com/draganm/go-lean/common/goja/fieldmapper"
+	"github.com/draganm/go-lean/common/tsdecl"
+	"github.com/stretchr/testify/require"
+)
+
+type item struct {
+	Name  string
+	Tags  []string
+	Attrs map[string]int
+	Next  *item
+}
+
+type store struct {
+	Items []item `lean:"items"`
+}
+
+func (s *store) Find(name string) (*item, error) {
+	return nil, nil
+}
+
+func (s *store) Each(fn func(item) bool) {}
+
+func (s *store) Sum(values ...float64) float64 {
+	return 0
+}
+
+func (s *store) Pair() (string, int) {
+	return "", 0
+}
+
+func TestTypes(t *testing.T) {
+	g := tsdecl.New(fieldmapper.FallbackFieldMapper{CamelCase: true})
+
+	for _, tc := range []struct {
+		typ      reflect.Type
+		expected string
+	}{
+		{globals.TypeOf[bool](), "boolean"},
+		{globals.TypeOf[uint16](), "number"},
+		{globals.TypeOf[*string](), "string"},
+		{globals.TypeOf[[]*int](), "number[]"},
+		{globals.TypeOf[[]byte](), "string | number[]"},
+		{globals.TypeOf[[][]byte](), "(string | number[])[]"},
+		{globals.TypeOf[map[string][]bool](), "Record<string, boolean[]>"},
+		{globals.TypeOf[any](), "any"},
+		{globals.TypeOf[error](), "Error"},
+		{globals.TypeOf[func(string) error](), "(arg1: string) => void"},
+		{globals.TypeOf[struct{ A int }](), "{ a: number }"},
+		{globals.TypeOf[*item](), "item"},
+	} {
+		require.Equal(t, tc.expected, g.Type(tc.typ), tc.typ.String())
+	}
+
+	require.Equal(t, "/** tsdecl_test.item */\ninterface item {\n  name: string\n  tags: string[]\n  attrs: Record<string, number>\n  next: item\n}\n", g.Interfaces())
+}
+
+func TestFunction(t *testing.T) {
+	g := tsdecl.New(fieldmapper.FallbackFieldMapper{})
+	fn := func(ctx context.Context, name string, opts ...int) (bool, error) {
+		return false, nil
+	}
+	require.Equal(t, "(arg1: string, ...arg2: number[]): boolean", g.Function(reflect.TypeOf(fn), 1))
+}
+
+func TestDeclare(t *testing.T) {
+	g := tsdecl.New(fieldmapper.FallbackFieldMapper{CamelCase: true})
+
+	decl := g.Declare(globals.Module{
+		Name:    "db",
+		Doc:     "The item store.",
+		Value:   &store{},
+		Members: map[string]string{"find": "Finds an item by name."},
+	}, 0)
+
+	require.Equal(t, `/**
+ * The item store.
+ */
+declare const db: {
+  items: item[]
+  each(arg1: (arg1: item) => boolean): void
+  /**
+   * Finds an item by name.
+   */
+  find(arg1: string): item
+  pair(): [string, number]
+  sum(...arg1: number[]): number
+}
+`, decl)
+
+	require.Equal(t, "declare const version: string\n", g.Declare(globals.Module{Name: "version", Value: "1.0"}, 0))
+	require.Equal(t, "declare const now: () => number\n", g.Declare(globals.Module{Name: "now", Declaration: "() => number"}, 0))
+	require.Equal(t, "declare const tx: any\n", g.Declare(globals.Module{Name: "tx", Value: func() globals.Values { return nil }}, 0))
+}
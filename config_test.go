@@ -0,0 +1,125 @@
+package lean_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/draganm/go-lean"
+	"github.com/draganm/go-lean/common/eventloop"
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlerConfig(t *testing.T) {
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sfs, err := fs.Sub(simple, "fixtures/config")
+	require.NoError(err)
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+
+	w, err := lean.Construct(
+		ctx,
+		sfs,
+		testr.New(t),
+		map[string]any{
+			"waitForRelease": func(loop *eventloop.EventLoop) *goja.Promise {
+				entered <- struct{}{}
+				return loop.Promise(func() (any, error) {
+					<-release
+					return nil, nil
+				})
+			},
+		},
+		lean.WithAuthenticator(func(r *http.Request) error {
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				return errors.New("invalid token")
+			}
+			return nil
+		}),
+	)
+	require.NoError(err)
+
+	post := func(contentType, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/upload", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		w.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("allowed request", func(t *testing.T) {
+		rec := post("application/json; charset=utf-8", "{}")
+		require.Equal(http.StatusOK, rec.Code)
+		require.Equal("ok", rec.Body.String())
+	})
+
+	t.Run("body too large", func(t *testing.T) {
+		rec := post("application/json", `{"too": "large"}`)
+		require.Equal(http.StatusRequestEntityTooLarge, rec.Code)
+	})
+
+	t.Run("content type not allowed", func(t *testing.T) {
+		rec := post("text/plain", "{}")
+		require.Equal(http.StatusUnsupportedMediaType, rec.Code)
+	})
+
+	t.Run("auth required", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		w.ServeHTTP(rec, httptest.NewRequest("GET", "/private", nil))
+		require.Equal(http.StatusUnauthorized, rec.Code)
+
+		rec = httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/private", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		w.ServeHTTP(rec, req)
+		require.Equal(http.StatusOK, rec.Code)
+		require.Equal("private, max-age=60", rec.Header().Get("Cache-Control"))
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		require.HTTPStatusCode(w.ServeHTTP, "GET", "/slow", nil, http.StatusServiceUnavailable)
+	})
+
+	t.Run("queue timeout", func(t *testing.T) {
+		done := make(chan int)
+		go func() {
+			rec := httptest.NewRecorder()
+			w.ServeHTTP(rec, httptest.NewRequest("GET", "/queued", nil))
+			done <- rec.Code
+		}()
+		<-entered
+
+		rec := httptest.NewRecorder()
+		w.ServeHTTP(rec, httptest.NewRequest("GET", "/queued", nil))
+		require.Equal(http.StatusServiceUnavailable, rec.Code)
+		require.Contains(rec.Body.String(), "too many concurrent requests")
+
+		close(release)
+		require.Equal(http.StatusOK, <-done)
+	})
+
+}
+
+func TestHandlerConfigRequiringAuthWithoutAuthenticator(t *testing.T) {
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sfs, err := fs.Sub(simple, "fixtures/config")
+	require.NoError(err)
+
+	_, err = lean.Construct(ctx, sfs, testr.New(t), map[string]any{})
+	require.ErrorContains(err, "no authenticator is configured")
+}
@@ -2,18 +2,26 @@ package cron
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"reflect"
 	"regexp"
 	"time"
 
 	"github.com/dop251/goja"
+	"github.com/draganm/go-lean/common/compiler"
+	"github.com/draganm/go-lean/common/eventloop"
 	"github.com/draganm/go-lean/common/globals"
-	"github.com/draganm/go-lean/common/goja/fieldmapper"
+	"github.com/draganm/go-lean/common/jsruntime"
+	"github.com/draganm/go-lean/common/jsstack"
+	"github.com/draganm/go-lean/common/limits"
 	"github.com/go-co-op/gocron"
 	"github.com/go-logr/logr"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
@@ -33,10 +41,21 @@ var (
 	}, []string{"cron"})
 )
 
-var cronRegexp = regexp.MustCompile(`^/cron/.+.js$`)
+var cronRegexp = regexp.MustCompile(`^/cron/.+.(js|ts)$`)
 
 var tracer = otel.Tracer("leancron")
 
+// JobName is the path of the cron job script, e.g. `/cron/cleanup.js`.
+type JobName string
+
+// InjectableTypes can be injected into providers of cron job globals.
+var InjectableTypes = []reflect.Type{
+	globals.TypeOf[context.Context](),
+	globals.TypeOf[JobName](),
+	globals.TypeOf[*goja.Runtime](),
+	globals.TypeOf[*eventloop.EventLoop](),
+}
+
 //
 
 type Builder struct {
@@ -59,13 +78,23 @@ func (b *Builder) Consume(pth string, getContent func() ([]byte, error)) bool {
 	return false
 }
 
-func (b *Builder) Start(ctx context.Context, log logr.Logger, gl globals.Globals) (err error) {
+// Start schedules the cron jobs, every run of a job gets a new runtime created by the factory.
+// The time budget of the factory limits is enforced for every run.
+func (b *Builder) Start(ctx context.Context, log logr.Logger, factory *jsruntime.Factory) (err error) {
 
 	if len(b.files) == 0 {
 		log.Info("no crons found")
 		return nil
 	}
 
+	env, err := factory.Environment("cron", nil)
+	if err != nil {
+		return err
+	}
+
+	gl := env.Globals
+	lim := env.Limits
+
 	scheduler := gocron.NewScheduler(time.Local)
 
 	defer func() {
@@ -83,6 +112,8 @@ func (b *Builder) Start(ctx context.Context, log logr.Logger, gl globals.Globals
 		Schedule         string        `lean:"schedule"`
 		AllowParallel    bool          `lean:"allowParallel"`
 		Run              goja.Callable `lean:"run"`
+		loop             *eventloop.EventLoop
+		finish           globals.Finish
 		durationObserver prometheus.Observer
 		successCounter   prometheus.Counter
 		failureCounter   prometheus.Counter
@@ -94,12 +125,19 @@ func (b *Builder) Start(ctx context.Context, log logr.Logger, gl globals.Globals
 			return fmt.Errorf("could not get data for %s: %w", pth, err)
 		}
 
+		prog, err := compiler.Compile(pth, string(data), false)
+		if err != nil {
+			return fmt.Errorf("could not compile %s: %w", pth, err)
+		}
+
 		getCronInfo := func(ctx context.Context) (*CronInfo, error) {
 
-			vm := goja.New()
-			vm.SetFieldNameMapper(fieldmapper.FallbackFieldMapper{})
+			vm, loop, err := env.New()
+			if err != nil {
+				return nil, err
+			}
 
-			autoWired, err := gl.AutoWire(ctx, vm)
+			autoWired, finish, err := gl.AutoWireFinish(InjectableTypes, ctx, JobName(pth), vm, loop)
 			if err != nil {
 				return nil, fmt.Errorf("could not autowire globals: %w", err)
 			}
@@ -107,31 +145,42 @@ func (b *Builder) Start(ctx context.Context, log logr.Logger, gl globals.Globals
 			for k, v := range autoWired {
 				err = vm.Set(k, v)
 				if err != nil {
-					return nil, fmt.Errorf("could not set global %s: %w", k, err)
+					err = fmt.Errorf("could not set global %s: %w", k, err)
+					return nil, errors.Join(err, finish(err))
 				}
 			}
-			_, err = vm.RunScript(pth, string(data))
+			err = compiler.RunScript(vm, prog)
 			if err != nil {
-				return nil, fmt.Errorf("could not run script %s: %w", pth, err)
+				err = fmt.Errorf("could not run script %s: %w", pth, err)
+				return nil, errors.Join(err, finish(err))
 			}
 
 			info := &CronInfo{}
 			err = vm.ExportTo(vm.GlobalObject(), info)
 			if err != nil {
-				return nil, fmt.Errorf("could not convert value to cron info: %w", err)
+				err = fmt.Errorf("could not convert value to cron info: %w", err)
+				return nil, errors.Join(err, finish(err))
 			}
 
+			info.loop = loop
+			info.finish = finish
 			info.durationObserver = executionDuration.WithLabelValues(pth)
 			info.successCounter = executionSuccessful.WithLabelValues(pth)
 			info.failureCounter = executionFailed.WithLabelValues(pth)
 			return info, nil
 		}
 
-		ci, err := getCronInfo(context.Background())
+		ci, err := getCronInfo(logr.NewContext(context.Background(), log.WithValues("cronJob", pth)))
 		if err != nil {
 			return fmt.Errorf("could not get cron info for %s: %w", pth, err)
 		}
 
+		// the runtime is only used to read the schedule
+		err = ci.finish(nil)
+		if err != nil {
+			return fmt.Errorf("could not finish globals of %s: %w", pth, err)
+		}
+
 		if ci.Schedule == "" {
 			return fmt.Errorf("cron %s does not have `schedule` set", pth)
 		}
@@ -144,7 +193,7 @@ func (b *Builder) Start(ctx context.Context, log logr.Logger, gl globals.Globals
 		sch.CronWithSeconds(ci.Schedule).DoWithJobDetails(func(job gocron.Job) {
 			log := log.WithValues("cronJob", pth)
 
-			ctx, span := tracer.Start(job.Context(), fmt.Sprintf("leancron: %s", pth))
+			ctx, span := tracer.Start(logr.NewContext(job.Context(), log), fmt.Sprintf("leancron: %s", pth))
 			defer span.End()
 
 			ci, err := getCronInfo(ctx)
@@ -155,12 +204,28 @@ func (b *Builder) Start(ctx context.Context, log logr.Logger, gl globals.Globals
 
 			startTime := time.Now()
 			log.Info("cron job started")
-			_, err = ci.Run(nil)
+			runCtx, budget := lim.StartBudget(ctx, ci.loop.Runtime())
+			_, err = ci.loop.Run(runCtx, func(*goja.Runtime) (goja.Value, error) {
+				return ci.Run(nil)
+			})
+			err = budget.Stop(err)
+			ferr := ci.finish(err)
+			if ferr != nil {
+				log.Error(ferr, "could not finish cron job globals")
+				if err == nil {
+					err = ferr
+				}
+			}
 			ci.durationObserver.Observe(time.Since(startTime).Seconds())
 			if err != nil {
 				ci.failureCounter.Inc()
-				log.Error(err, "cron job failed")
-				span.RecordError(err)
+				frames := jsstack.FromError(err)
+				violation := limits.Report(pth, err)
+				if violation != nil {
+					log = log.WithValues("limit", violation.Error())
+				}
+				log.Error(err, "cron job failed", "jsStack", frames)
+				span.RecordError(err, trace.WithAttributes(attribute.String("exception.stacktrace", jsstack.Format(frames))))
 				return
 			}
 			ci.successCounter.Inc()
@@ -0,0 +1,68 @@
+package lean_test
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/draganm/go-lean"
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/testr"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+type environmentItem struct {
+	Name string
+	Size int
+}
+
+func TestEnvironment(t *testing.T) {
+	t.Run("web, cron and metrics share libraries, field names and globals", func(t *testing.T) {
+		require := require.New(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		sfs, err := fs.Sub(simple, "fixtures/environment")
+		require.NoError(err)
+
+		reported := make(chan string, 10)
+
+		// metrics are collected when they are unregistered after the test
+		w, err := lean.Construct(ctx, sfs, logr.Discard(), map[string]any{
+			"item": func() *environmentItem {
+				return &environmentItem{Name: "box", Size: 3}
+			},
+			"report": func(s string) {
+				reported <- s
+			},
+		}, lean.WithCamelCase())
+		require.NoError(err)
+
+		require.HTTPBodyContains(w.ServeHTTP, "GET", "/values", nil, "box:3")
+
+		select {
+		case v := <-reported:
+			require.Equal("box:3", v)
+		case <-time.After(3 * time.Second):
+			require.Fail("cron job has not reported")
+		}
+
+		metrics := findMetrics(t, "environment_value", dto.MetricType_GAUGE)
+		require.Len(metrics, 1)
+		require.Equal(3.0, metrics[0].GetGauge().GetValue())
+	})
+
+	t.Run("cron errors are returned", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		sfs, err := fs.Sub(simple, "fixtures/environment-broken")
+		require.NoError(t, err)
+
+		_, err = lean.Construct(ctx, sfs, testr.New(t), map[string]any{})
+		require.ErrorContains(t, err, "could not start cron jobs: cron /cron/unscheduled.js does not have `schedule` set")
+	})
+}
@@ -0,0 +1,46 @@
+package lean_test
+
+import (
+	"context"
+	"io/fs"
+	"net/http"
+	"testing"
+
+	"github.com/draganm/go-lean"
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDevModeErrorPage(t *testing.T) {
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sfs, err := fs.Sub(simple, "fixtures/errors")
+	require.NoError(err)
+
+	w, err := lean.Construct(ctx, sfs, testr.New(t), map[string]any{}, lean.WithDevMode())
+	require.NoError(err)
+
+	require.HTTPStatusCode(w.ServeHTTP, "GET", "/fail", nil, http.StatusInternalServerError)
+	require.HTTPBodyContains(w.ServeHTTP, "GET", "/fail", nil, "at validate (/lib/validate.ts:3:")
+	require.HTTPBodyContains(w.ServeHTTP, "GET", "/fail", nil, "<pre>Error: value must not be empty</pre>")
+	require.HTTPBodyContains(w.ServeHTTP, "GET", "/fail", nil, "throw new Error(&#34;value must not be empty&#34;)")
+}
+
+func TestErrorPageIsNotShownOutsideOfDevMode(t *testing.T) {
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sfs, err := fs.Sub(simple, "fixtures/errors")
+	require.NoError(err)
+
+	w, err := lean.Construct(ctx, sfs, testr.New(t), map[string]any{})
+	require.NoError(err)
+
+	require.HTTPStatusCode(w.ServeHTTP, "GET", "/fail", nil, http.StatusInternalServerError)
+	require.HTTPBodyNotContains(w.ServeHTTP, "GET", "/fail", nil, "validate.ts")
+}
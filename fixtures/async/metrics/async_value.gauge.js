@@ -0,0 +1,6 @@
+description = "value collected by an async function"
+
+async function collect() {
+    await new Promise((resolve) => setImmediate(resolve))
+    return 7
+}
@@ -0,0 +1,4 @@
+async function handler(w, r) {
+    await null
+    returnStatus(418, "teapot")
+}
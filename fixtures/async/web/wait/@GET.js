@@ -0,0 +1,7 @@
+const sleep = (ms) => new Promise((resolve) => setTimeout(resolve, ms))
+
+async function handler(w, r) {
+    await sleep(10)
+    const v = await fetchValue()
+    w.Write(`waited for ${v}`)
+}
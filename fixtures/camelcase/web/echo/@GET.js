@@ -0,0 +1,4 @@
+function handler(w, r) {
+    w.header().set("content-type", "text/plain")
+    w.write(`${r.method} ${r.url.path} ${r.header.get("x-name")}`)
+}
@@ -0,0 +1,8 @@
+const config = {
+    requireAuth: true,
+    cacheControl: "private, max-age=60",
+}
+
+function handler(w, r) {
+    w.Write("secret")
+}
@@ -0,0 +1,8 @@
+const config = {
+    maxConcurrency: 1,
+    queueTimeout: "20ms",
+}
+
+function handler(w, r) {
+    return waitForRelease()
+}
@@ -0,0 +1,7 @@
+const config = {
+    timeout: "50ms",
+}
+
+function handler(w, r) {
+    while (true) { }
+}
@@ -0,0 +1,9 @@
+const config = {
+    maxBodySize: 10,
+    allowedContentTypes: ["application/json"],
+    maxConcurrency: 2,
+}
+
+function handler(w, r) {
+    w.Write("ok")
+}
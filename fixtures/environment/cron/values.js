@@ -0,0 +1,7 @@
+schedule = "* * * * * *"
+
+const { describe } = require("/lib/values")
+
+function run() {
+    report(describe(item()))
+}
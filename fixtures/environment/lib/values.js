@@ -0,0 +1 @@
+exports.describe = (item) => `${item.name}:${item.size}`
@@ -0,0 +1,8 @@
+const { describe } = require("/lib/values")
+
+description = "value computed by a library"
+
+function collect() {
+    log.info("collecting", "item", describe(item()))
+    return item().size
+}
@@ -0,0 +1,5 @@
+const { describe } = require("/lib/values")
+
+function handler(w, r) {
+    w.write(describe(item()))
+}
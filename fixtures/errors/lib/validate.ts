@@ -0,0 +1,6 @@
+export function validate(value: string): string {
+    if (value === "") {
+        throw new Error("value must not be empty")
+    }
+    return value
+}
@@ -0,0 +1,6 @@
+import { validate } from "/lib/validate"
+
+export async function handler(w: any, r: any) {
+    await null
+    w.Write(validate(""))
+}
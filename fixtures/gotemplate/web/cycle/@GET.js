@@ -0,0 +1,7 @@
+function handler(w, r) {
+    try {
+        gotemplate.render("index", {}, { layout: false })
+    } catch (e) {
+        w.Write(String(e))
+    }
+}
@@ -0,0 +1,3 @@
+function handler(w, r) {
+    gotemplate.render("index", { q: `"><script>x</script>` }, { layout: false })
+}
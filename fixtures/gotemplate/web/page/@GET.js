@@ -0,0 +1,3 @@
+function handler(w, r) {
+    gotemplate.render("index", { title: "Items", items: ["a", "b"] })
+}
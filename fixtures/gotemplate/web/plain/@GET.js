@@ -0,0 +1,3 @@
+function handler(w, r) {
+    w.Write(gotemplate.renderToString("index.gohtml", { name: "lean" }, { layout: false }))
+}
@@ -0,0 +1,3 @@
+function handler(w, r) {
+    gotemplate.render("index", [1, 2], { fragment: htmx.target == "list" ? "list" : "" })
+}
@@ -0,0 +1,5 @@
+function handler(w, r) {
+    htmx.pushURL("/rows")
+    htmx.triggerAfterSettle("saved")
+    w.Write("<p>saved</p>" + htmx.oob("cart-count", "3", { swap: "innerHTML", tag: "span" }))
+}
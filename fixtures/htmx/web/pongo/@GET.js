@@ -0,0 +1,3 @@
+function handler(w, r) {
+    pongo2.render("index", { items: [1, 2] }, { fragment: htmx.isRequest ? "list" : "" })
+}
@@ -0,0 +1,9 @@
+function handler(w, r) {
+    const data = { title: "Rows", rows: [{ name: "a" }, { name: "b" }] }
+    if (htmx.isRequest) {
+        htmx.triggerEvents({ rowsLoaded: { count: data.rows.length } })
+        mustache.render("index", data, { fragment: "rows" })
+        return
+    }
+    mustache.render("index", data)
+}
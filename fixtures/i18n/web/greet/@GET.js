@@ -0,0 +1,4 @@
+function handler(w, r) {
+    const items = [1, 2, 5].map((count) => t("cart.items", { count })).join(", ")
+    w.Write(`${i18n.locale}: ${t("greeting", { name: "Ana" })} ${items}`)
+}
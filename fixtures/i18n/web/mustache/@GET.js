@@ -0,0 +1,3 @@
+function handler(w, r) {
+    mustache.render("index", { name: "<Ana>", count: 1 })
+}
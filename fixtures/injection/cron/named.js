@@ -0,0 +1,5 @@
+schedule = "* * * * * *"
+
+function run() {
+  report(jobName())
+}
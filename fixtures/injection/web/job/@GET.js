@@ -0,0 +1,3 @@
+function handler(w, r) {
+  w.Write(typeof jobName)
+}
@@ -0,0 +1,7 @@
+let count = 0
+
+function handler(w, r) {
+  count++
+  globalThis.leaked = (globalThis.leaked || 0) + 1
+  w.Write(JSON.stringify({ count, leaked: globalThis.leaked }))
+}
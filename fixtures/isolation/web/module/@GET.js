@@ -0,0 +1,4 @@
+function handler(w, r) {
+  const counter = require("/lib/counter")
+  w.Write(String(counter.next()))
+}
@@ -0,0 +1,11 @@
+const seen = []
+
+function handler(w, r) {
+  try {
+    seen.push(r.URL.Path)
+  } catch (e) {
+    w.Write("frozen: " + e.constructor.name)
+    return
+  }
+  w.Write(String(seen.length))
+}
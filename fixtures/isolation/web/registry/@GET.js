@@ -0,0 +1,12 @@
+require("/lib/registry")
+
+function handler(w, r) {
+  const { items } = require("/lib/registry")
+  try {
+    items.push(r.URL.Path)
+  } catch (e) {
+    w.Write("frozen: " + e.constructor.name)
+    return
+  }
+  w.Write(String(items.length))
+}
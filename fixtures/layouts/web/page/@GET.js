@@ -0,0 +1,3 @@
+function handler(w, r) {
+    mustache.render("index", { title: "Page", name: "lean" })
+}
@@ -0,0 +1,4 @@
+function handler(w, r) {
+    const layout = r.URL.Query().Get("layout")
+    mustache.render("index", { name: "lean" }, { layout: layout === "none" ? false : layout })
+}
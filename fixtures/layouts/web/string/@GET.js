@@ -0,0 +1,5 @@
+function handler(w, r) {
+    const wrapped = mustache.renderToString("index", { name: "lean" }, { layout: "/layouts/minimal" })
+    const bare = mustache.renderToString("index", { name: "lean" }, { layout: false })
+    w.Write(`${wrapped} ${bare}`)
+}
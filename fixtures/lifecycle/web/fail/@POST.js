@@ -0,0 +1,4 @@
+function handler(w, r) {
+  tx.exec("insert")
+  throw new Error("validation failed")
+}
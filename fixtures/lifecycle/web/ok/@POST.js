@@ -0,0 +1,4 @@
+function handler(w, r) {
+  tx.exec("insert")
+  w.Write("ok")
+}
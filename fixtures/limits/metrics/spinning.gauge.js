@@ -0,0 +1,5 @@
+description = "never finishes collecting"
+
+function collect() {
+  while (true) {}
+}
@@ -0,0 +1,7 @@
+function handler(w, r) {
+  try {
+    w.Write("x".repeat(1000))
+  } catch (e) {
+    // ignored by the script, still reported
+  }
+}
@@ -0,0 +1,4 @@
+function handler(w, r) {
+  w.Write("small")
+  w.Write("x".repeat(1000))
+}
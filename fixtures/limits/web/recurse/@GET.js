@@ -0,0 +1,7 @@
+function recurse(n) {
+  return recurse(n + 1) + 1
+}
+
+function handler(w, r) {
+  w.Write(String(recurse(0)))
+}
@@ -0,0 +1,3 @@
+function handler(w, r) {
+  while (true) {}
+}
@@ -0,0 +1,4 @@
+async function handler(w, r) {
+  await new Promise((resolve) => setTimeout(resolve, 10000))
+  w.Write("waited")
+}
@@ -0,0 +1,5 @@
+schedule = "* * * * * *"
+
+function run() {
+  log.info("cron tick", { count: 1 })
+}
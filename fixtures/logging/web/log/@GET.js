@@ -0,0 +1,10 @@
+function handler(w, r) {
+  log.debug("debug message", "verbose", true)
+  log.info("user created", { user: { id: 42, name: "jane" } })
+  log.warn("slow request", "duration", 1200)
+  log.error("could not notify", new Error("mailer down"), { attempt: 3 })
+  log.Info("logr style", "bar", "baz")
+  log.V(1).WithName("orders").WithValues("order", { id: 7 }).Info("derived logger")
+  log.Info("enabled", "default", log.Enabled(), "verbose", log.V(2).Enabled())
+  w.Write("logged")
+}
@@ -0,0 +1,11 @@
+schedule = "* * * * * *"
+
+async function run() {
+    await mail.send({
+        to: ["ops@example.com", "dev@example.com"],
+        bcc: "audit@example.com",
+        subject: "Report",
+        htmlTemplate: "emails/report.pongo2",
+        data: { jobs: 2 },
+    })
+}
@@ -0,0 +1,8 @@
+async function handler(w, r) {
+    try {
+        await mail.send({ subject: "nobody", text: "lost" })
+    } catch (e) {
+        w.WriteHeader(400)
+        w.Write(`${e}`)
+    }
+}
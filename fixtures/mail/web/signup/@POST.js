@@ -0,0 +1,12 @@
+async function handler(w, r) {
+    const name = r.URL.Query().Get("name")
+    const id = await mail.send({
+        to: `${name}@example.com`,
+        subject: `Welcome ${name}`,
+        htmlTemplate: "emails/welcome.mustache",
+        textTemplate: "emails/welcome.text.mustache",
+        data: { name },
+        attachments: [{ filename: "terms.txt", contentType: "text/plain", content: "Be nice." }],
+    })
+    w.Write(id)
+}
@@ -0,0 +1,4 @@
+function handler(w, r) {
+    w.Header().Set("content-type", "text/html; charset=utf-8")
+    w.Write(markdown.render("**" + r.URL.Query().Get("text") + "**"))
+}
@@ -0,0 +1,4 @@
+let count = 0
+
+exports.id = module.id
+exports.next = () => ++count
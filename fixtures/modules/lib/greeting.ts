@@ -0,0 +1,8 @@
+export interface Greeting {
+    greeting: string
+    name: string
+}
+
+export function greet(g: Greeting): string {
+    return `${g.greeting} ${g.name}`
+}
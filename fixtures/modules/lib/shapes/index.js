@@ -0,0 +1,3 @@
+const data = require("./data.json")
+
+exports.names = () => data.shapes.join(",")
@@ -0,0 +1,5 @@
+export const description: string = "value collected by a typescript function"
+
+export function collect(): number {
+    return 3
+}
@@ -0,0 +1,7 @@
+function handler(w, r) {
+    try {
+        require("/lib/cycle/a")
+    } catch (e) {
+        w.Write(String(e))
+    }
+}
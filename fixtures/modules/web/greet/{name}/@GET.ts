@@ -0,0 +1,9 @@
+import { greet } from "/lib/greeting"
+
+type Params = {
+    name: string
+}
+
+export function handler(w: any, r: any, params: Params) {
+    w.Write(greet({ greeting: "hello", name: params.name }))
+}
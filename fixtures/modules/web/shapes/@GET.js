@@ -0,0 +1,7 @@
+const { names } = require("../../lib/shapes")
+const counter = require("/lib/counter")
+const sameCounter = require("../../lib/counter.js")
+
+function handler(w, r) {
+    w.Write(`${names()} ${counter === sameCounter} ${counter.id}`)
+}
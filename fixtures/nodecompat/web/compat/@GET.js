@@ -0,0 +1,16 @@
+function handler(w, r) {
+    console.log("handling %s", "compat")
+
+    const url = new URL("https://example.com/path?shape=circle")
+    const encoded = new TextEncoder().encode("héllo")
+
+    w.Write(JSON.stringify({
+        host: url.host,
+        shape: url.searchParams.get("shape"),
+        base64: Buffer.from("hello").toString("base64"),
+        encodedLength: encoded.length,
+        decoded: new TextDecoder().decode(encoded),
+        allowed: process.env.LEAN_TEST_ALLOWED,
+        denied: process.env.LEAN_TEST_DENIED,
+    }))
+}
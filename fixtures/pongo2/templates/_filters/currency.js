@@ -0,0 +1,3 @@
+function filter(value, symbol) {
+    return `${value.toFixed(2)} ${symbol || "EUR"}`
+}
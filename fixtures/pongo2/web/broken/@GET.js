@@ -0,0 +1,7 @@
+function handler(w, r) {
+    try {
+        pongo2.render("broken", {})
+    } catch (e) {
+        w.Write(String(e))
+    }
+}
@@ -0,0 +1,5 @@
+function handler(w, r) {
+    const page = pongo2.renderToString("index", { name: "lean" })
+    const box = pongo2.renderToString("/shared/box.pongo2", { name: "lean" })
+    w.Write(`${page} ${box}`)
+}
@@ -0,0 +1,3 @@
+function filter(value) {
+    while (true) { }
+}
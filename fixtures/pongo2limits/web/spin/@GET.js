@@ -0,0 +1,3 @@
+function handler(w, r) {
+    pongo2.render("index", { value: 1 })
+}
@@ -0,0 +1,5 @@
+description = "visits counted by the handler"
+
+function collect() {
+  return shared.get("visits") || 0
+}
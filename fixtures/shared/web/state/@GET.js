@@ -0,0 +1,27 @@
+function handler(w, r) {
+  shared.set("user", { name: "jane", roles: ["admin"] })
+  const acquired = shared.cas("lock", undefined, "first", "1m")
+  const reacquired = shared.cas("lock", undefined, "second")
+  const swapped = shared.cas("lock", "first", "third")
+  const lock = shared.get("lock")
+  const deleted = shared.delete("lock")
+
+  let error = null
+  try {
+    shared.incr("user")
+  } catch (e) {
+    error = e.message
+  }
+
+  w.Write(JSON.stringify({
+    user: shared.get("user"),
+    missing: shared.get("missing") === undefined,
+    acquired,
+    reacquired,
+    swapped,
+    lock,
+    deleted,
+    error,
+    keys: shared.keys(),
+  }))
+}
@@ -0,0 +1,3 @@
+function handler(w, r) {
+    return mustache.stream("index", { title: "Slow", rows: fetchRows(), footer: "end" })
+}
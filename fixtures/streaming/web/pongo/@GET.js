@@ -0,0 +1,3 @@
+function handler(w, r) {
+    return pongo2.stream("index", { title: "Slow", rows: fetchRows(), footer: "end" })
+}
@@ -0,0 +1,3 @@
+function handler(w, r) {
+    return pongo2.stream("/pongo/index", { title: "Never", rows: new Promise((resolve) => setTimeout(resolve, 10000)), footer: "end" })
+}
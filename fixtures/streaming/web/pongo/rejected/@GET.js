@@ -0,0 +1,7 @@
+async function handler(w, r) {
+    try {
+        await pongo2.stream("/pongo/index", { title: "Rejected", rows: Promise.reject(new Error("no rows")), footer: "end" })
+    } catch (e) {
+        w.Write(" rejected: " + e.message)
+    }
+}
@@ -0,0 +1,9 @@
+schedule = "* * * * * *"
+
+function run() {
+    report([
+        mustache.renderToString("emails/welcome", { name: "cron" }),
+        pongo2.renderToString("reports/jobs", { count: 2 }),
+        gotemplate.renderToString("/templates/reports/summary", { count: 3 }),
+    ].join("\n"))
+}
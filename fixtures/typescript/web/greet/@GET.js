@@ -0,0 +1,3 @@
+function handler(w, r) {
+  w.Write(`${greeter.Greet("lean")} ${counter.count}`)
+}
@@ -0,0 +1,92 @@
+package gotemplate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path"
+	"sync"
+
+	"github.com/draganm/go-lean/common/globals"
+	"github.com/draganm/go-lean/web/types"
+)
+
+type Builder struct {
+	names types.TemplateFiles
+	errs  []error
+	files map[string]func() ([]byte, error)
+}
+
+func NewBuilder() *Builder {
+	return &Builder{
+		names: types.TemplateFiles{},
+		files: map[string]func() ([]byte, error){},
+	}
+}
+
+func (b *Builder) Consume(pth string, getContent func() ([]byte, error)) bool {
+	name, isTemplate := types.TemplateName(pth)
+	if !isTemplate {
+		return false
+	}
+
+	_, fileName := path.Split(name)
+
+	if templateRegexp.MatchString(fileName) {
+		err := b.names.Add(name, pth)
+		if err != nil {
+			b.errs = append(b.errs, err)
+		}
+		b.files[name] = getContent
+		return true
+	}
+
+	return false
+}
+
+// Create returns the provider of the `gotemplate` global. Funcs are available
+// in all templates, in addition to `include`.
+func (b *Builder) Create(funcs template.FuncMap) (GoTemplateProvider, error) {
+	if len(b.errs) != 0 {
+		return nil, errors.Join(b.errs...)
+	}
+
+	sources := map[string]string{}
+
+	for pth, getContent := range b.files {
+		data, err := getContent()
+		if err != nil {
+			return nil, fmt.Errorf("could not get content of %s: %w", pth, err)
+		}
+
+		fileDir, fileName := path.Split(pth)
+		name := templateRegexp.FindStringSubmatch(fileName)[1]
+		sources[path.Join(fileDir, name)] = string(data)
+	}
+
+	_, reserved := funcs["include"]
+	if reserved {
+		return nil, fmt.Errorf("include is a reserved template function")
+	}
+
+	ts := &templateSet{
+		sources: sources,
+		funcs:   template.FuncMap{"include": includeUnavailable},
+		cached:  map[string]*template.Template{},
+		mu:      &sync.RWMutex{},
+	}
+
+	for k, v := range funcs {
+		ts.funcs[k] = v
+	}
+
+	return func(ctx context.Context, handlerPath types.HandlerPath, w http.ResponseWriter) globals.Values {
+		scope := path.Dir(string(handlerPath))
+		return map[string]any{
+			"render":         renderTemplateForScope(ctx, ts, scope, w),
+			"renderToString": renderTemplateForScopeToString(ctx, ts, scope),
+		}
+	}, nil
+}
@@ -0,0 +1,36 @@
+package gotemplate
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+
+	"github.com/draganm/go-lean/common/globals"
+	"github.com/draganm/go-lean/web/types"
+	"go.opentelemetry.io/otel"
+)
+
+var tracer = otel.Tracer("github.com/draganm/go-lean/leanweb/gotemplate")
+
+var templateRegexp = regexp.MustCompile(`^(.+)\.gohtml$`)
+
+type GoTemplateProvider func(ctx context.Context, handlerPath types.HandlerPath, w http.ResponseWriter) globals.Values
+
+// RenderToString renders the template without a layout, resolving its name relative to handlerPath.
+func (p GoTemplateProvider) RenderToString(ctx context.Context, handlerPath types.HandlerPath, name string, data any) (string, error) {
+	render := p(ctx, handlerPath, nil)["renderToString"].(func(string, any, *RenderOptions) (string, error))
+	return render(name, data, &RenderOptions{Layout: false})
+}
+
+// StandaloneProvider provides `gotemplate` to cron jobs and metrics. Without a response only `renderToString`
+// is there, it resolves names relative to /templates and renders them in the `_layout.gohtml` of /templates.
+type StandaloneProvider func(ctx context.Context) globals.Values
+
+// Standalone returns the provider of `gotemplate` without a response.
+func (p GoTemplateProvider) Standalone() StandaloneProvider {
+	return func(ctx context.Context) globals.Values {
+		return globals.Values{
+			"renderToString": p(ctx, types.StandaloneHandlerPath, nil)["renderToString"],
+		}
+	}
+}
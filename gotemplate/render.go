@@ -0,0 +1,233 @@
+package gotemplate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/draganm/go-lean/web/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RenderOptions of Go templates, the optional third argument of `render` and `renderToString`.
+type RenderOptions struct {
+	// Layout is the name of the template whose blocks the `{{ define }}`s of the rendered template
+	// override, or false for none. Defaults to the nearest `_layout.gohtml`.
+	Layout any `lean:"layout"`
+
+	// Fragment is the name of a template defined by the rendered template or its layout,
+	// e.g. a block. Only the fragment is rendered, e.g. for htmx requests.
+	Fragment string `lean:"fragment"`
+}
+
+func includeUnavailable(name string, data any) (template.HTML, error) {
+	return "", errors.New("include is only available while rendering")
+}
+
+type templateSet struct {
+	sources map[string]string
+	funcs   template.FuncMap
+	cached  map[string]*template.Template
+	mu      *sync.RWMutex
+}
+
+// resolve returns the absolute name of a template,
+// names are relative to the scope unless they start with `/`.
+func resolve(scope, name string) string {
+	name = strings.TrimSuffix(name, ".gohtml")
+	if !strings.HasPrefix(name, "/") {
+		name = path.Join(scope, name)
+	}
+	return path.Clean(name)
+}
+
+func (ts *templateSet) source(name string) (string, error) {
+	src, found := ts.sources[name]
+	if !found {
+		return "", fmt.Errorf("could not find template %s", name)
+	}
+	return src, nil
+}
+
+// parse returns the template, parsed into its layout if there is one.
+// Parsed templates are never executed, so that they can be cloned.
+func (ts *templateSet) parse(name, layout string) (*template.Template, error) {
+	key := name + "\x00" + layout
+
+	ts.mu.RLock()
+	t, found := ts.cached[key]
+	ts.mu.RUnlock()
+	if found {
+		return t, nil
+	}
+
+	src, err := ts.source(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if layout == "" {
+		t, err = template.New(name).Funcs(ts.funcs).Parse(src)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse template: %w", err)
+		}
+	} else {
+		layoutSrc, err := ts.source(layout)
+		if err != nil {
+			return nil, err
+		}
+
+		t, err = template.New(layout).Funcs(ts.funcs).Parse(layoutSrc)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse layout: %w", err)
+		}
+
+		// definitions of the template override blocks of the layout
+		_, err = t.New(name).Parse(src)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse template: %w", err)
+		}
+	}
+
+	ts.mu.Lock()
+	ts.cached[key] = t
+	ts.mu.Unlock()
+
+	return t, nil
+}
+
+// layout returns the layout the template is rendered in, empty if there is none.
+func (ts *templateSet) layout(scope, name string, opts *RenderOptions) (string, error) {
+	var option any
+	if opts != nil {
+		option = opts.Layout
+	}
+
+	return types.Layout(name, option, func(layout string) string {
+		return resolve(scope, layout)
+	}, func(layout string) bool {
+		_, found := ts.sources[layout]
+		return found
+	})
+}
+
+// maxIncludeDepth limits nested includes, every include executes the template on its own,
+// so the recursion limit of html/template doesn't apply to templates including each other.
+const maxIncludeDepth = 100
+
+type includeDepthError struct {
+	name string
+}
+
+func (e *includeDepthError) Error() string {
+	return fmt.Sprintf("could not include %s: includes are nested deeper than %d", e.name, maxIncludeDepth)
+}
+
+// execute renders the template, depth is the number of includes it is nested in.
+func (ts *templateSet) execute(scope string, w io.Writer, name string, data any, opts *RenderOptions, depth int) error {
+	resolved := resolve(scope, name)
+
+	layout, err := ts.layout(scope, resolved, opts)
+	if err != nil {
+		return err
+	}
+
+	t, err := ts.parse(resolved, layout)
+	if err != nil {
+		return fmt.Errorf("could not get/parse template %s in scope %s: %w", name, scope, err)
+	}
+
+	t, err = t.Clone()
+	if err != nil {
+		return fmt.Errorf("could not clone template %s: %w", name, err)
+	}
+
+	t.Funcs(template.FuncMap{"include": ts.include(scope, depth+1)})
+
+	if opts != nil && opts.Fragment != "" {
+		if t.Lookup(opts.Fragment) == nil {
+			return fmt.Errorf("template %s has no fragment %s", name, opts.Fragment)
+		}
+		err = t.ExecuteTemplate(w, opts.Fragment, data)
+	} else {
+		err = t.Execute(w, data)
+	}
+	if err != nil {
+		return fmt.Errorf("could not render template %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// include renders a template without a layout, e.g. `{{ include "row" . }}`.
+func (ts *templateSet) include(scope string, depth int) func(name string, data any) (template.HTML, error) {
+	return func(name string, data any) (template.HTML, error) {
+		if depth > maxIncludeDepth {
+			return "", &includeDepthError{name: name}
+		}
+
+		buf := &bytes.Buffer{}
+		err := ts.execute(scope, buf, name, data, &RenderOptions{Layout: false}, depth)
+		// the error is reported once, instead of wrapped by every include
+		de := &includeDepthError{}
+		if errors.As(err, &de) {
+			return "", de
+		}
+		if err != nil {
+			return "", err
+		}
+		return template.HTML(buf.String()), nil
+	}
+}
+
+func renderTemplateForScope(ctx context.Context, ts *templateSet, scope string, w io.Writer) func(name string, data any, opts *RenderOptions) error {
+
+	return func(name string, data any, opts *RenderOptions) error {
+		_, span := tracer.Start(ctx, fmt.Sprintf("gotemplate.RenderTemplate %s", name),
+			trace.WithAttributes(
+				attribute.String("template", name),
+			),
+		)
+
+		defer span.End()
+
+		err := ts.execute(scope, w, name, data, opts, 0)
+		if err != nil {
+			span.RecordError(err)
+			return err
+		}
+
+		return nil
+	}
+
+}
+
+func renderTemplateForScopeToString(ctx context.Context, ts *templateSet, scope string) func(name string, data any, opts *RenderOptions) (string, error) {
+
+	return func(name string, data any, opts *RenderOptions) (string, error) {
+		_, span := tracer.Start(ctx, fmt.Sprintf("gotemplate.RenderTemplateToString %s", name),
+			trace.WithAttributes(
+				attribute.String("template", name),
+			),
+		)
+
+		defer span.End()
+
+		buf := &bytes.Buffer{}
+		err := ts.execute(scope, buf, name, data, opts, 0)
+		if err != nil {
+			span.RecordError(err)
+			return "", err
+		}
+
+		return buf.String(), nil
+	}
+
+}
@@ -0,0 +1,54 @@
+package lean_test
+
+import (
+	"context"
+	"html/template"
+	"io/fs"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/draganm/go-lean"
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoTemplates(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sfs, err := fs.Sub(simple, "fixtures/gotemplate")
+	require.NoError(t, err)
+
+	w, err := lean.Construct(ctx, sfs, testr.New(t), map[string]any{}, lean.WithTemplateFuncs(template.FuncMap{
+		"shout": strings.ToUpper,
+	}))
+	require.NoError(t, err)
+
+	body := func(t *testing.T, target string) string {
+		rec := httptest.NewRecorder()
+		w.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		require.Equal(t, 200, rec.Code, rec.Body.String())
+		return rec.Body.String()
+	}
+
+	t.Run("templates override blocks of the nearest layout", func(t *testing.T) {
+		require.Equal(t, "<html><title>Items</title><body><ul><li>a</li><li>b</li></ul></body></html>\n", body(t, "/page"))
+	})
+
+	t.Run("functions can be added from Go", func(t *testing.T) {
+		require.Equal(t, "plain LEAN", body(t, "/plain"))
+	})
+
+	t.Run("values are escaped depending on the context", func(t *testing.T) {
+		require.Equal(t, `<a href="/search?q=%22%3e%3cscript%3ex%3c%2fscript%3e" onclick="alert(&#34;\&#34;\u003e\u003cscript\u003ex\u003c/script\u003e&#34;)">&#34;&gt;&lt;script&gt;x&lt;/script&gt;</a>`, body(t, "/escaping"))
+	})
+
+	t.Run("errors include line numbers", func(t *testing.T) {
+		require.Contains(t, body(t, "/broken"), "/broken/index:2: missing value for if")
+	})
+
+	t.Run("templates including themselves fail instead of overflowing the stack", func(t *testing.T) {
+		require.Contains(t, body(t, "/cycle"), "includes are nested deeper than 100")
+	})
+}
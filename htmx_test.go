@@ -0,0 +1,62 @@
+package lean_test
+
+import (
+	"context"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/draganm/go-lean"
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHtmx(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sfs, err := fs.Sub(simple, "fixtures/htmx")
+	require.NoError(t, err)
+
+	w, err := lean.Construct(ctx, sfs, testr.New(t), map[string]any{})
+	require.NoError(t, err)
+
+	serve := func(t *testing.T, method, target string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		w.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		return rec
+	}
+
+	htmxRequest := map[string]string{"HX-Request": "true"}
+
+	t.Run("mustache sections are rendered as fragments for htmx requests", func(t *testing.T) {
+		require.Equal(t, "<html><h1>Rows</h1><ul><li>a</li><li>b</li></ul></html>", serve(t, "GET", "/rows", nil).Body.String())
+
+		rec := serve(t, "GET", "/rows", htmxRequest)
+		require.Equal(t, "<li>a</li><li>b</li>", rec.Body.String())
+		require.Equal(t, `{"rowsLoaded":{"count":2}}`, rec.Header().Get("HX-Trigger"))
+	})
+
+	t.Run("pongo2 blocks are rendered as fragments", func(t *testing.T) {
+		require.Equal(t, "<html><ul><li>1</li><li>2</li></ul></html>", serve(t, "GET", "/pongo", nil).Body.String())
+		require.Equal(t, "<ul><li>1</li><li>2</li></ul>", serve(t, "GET", "/pongo", htmxRequest).Body.String())
+	})
+
+	t.Run("defined Go templates are rendered as fragments", func(t *testing.T) {
+		require.Equal(t, "<html><ul><li>1</li><li>2</li></ul></html>", serve(t, "GET", "/go", nil).Body.String())
+		require.Equal(t, "<ul><li>1</li><li>2</li></ul>", serve(t, "GET", "/go", map[string]string{"HX-Request": "true", "HX-Target": "list"}).Body.String())
+	})
+
+	t.Run("response headers and out of band swaps", func(t *testing.T) {
+		rec := serve(t, "POST", "/oob", htmxRequest)
+		require.Equal(t, `<p>saved</p><span id="cart-count" hx-swap-oob="innerHTML">3</span>`, rec.Body.String())
+		require.Equal(t, "/rows", rec.Header().Get("HX-Push-Url"))
+		require.Equal(t, "saved", rec.Header().Get("HX-Trigger-After-Settle"))
+	})
+}
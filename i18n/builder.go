@@ -0,0 +1,81 @@
+package i18n
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+
+	"golang.org/x/text/language"
+)
+
+var catalogRegexp = regexp.MustCompile(`^/i18n/([^/]+)\.(json|po)$`)
+
+type Builder struct {
+	files map[string]func() ([]byte, error)
+}
+
+func NewBuilder() *Builder {
+	return &Builder{
+		files: map[string]func() ([]byte, error){},
+	}
+}
+
+func (b *Builder) Consume(pth string, getContent func() ([]byte, error)) bool {
+	if !catalogRegexp.MatchString(pth) {
+		return false
+	}
+
+	b.files[pth] = getContent
+	return true
+}
+
+// Create parses the catalogs, named by their locale, e.g. `/i18n/de-AT.json` or `/i18n/fr.po`.
+func (b *Builder) Create(opts Options) (*Catalog, error) {
+	messages := map[string]map[string]message{}
+	files := map[string]string{}
+
+	for pth, getContent := range b.files {
+		submatches := catalogRegexp.FindStringSubmatch(pth)
+
+		tag, err := language.Parse(submatches[1])
+		if err != nil {
+			return nil, fmt.Errorf("catalog %s is not named by a locale: %w", pth, err)
+		}
+		locale := tag.String()
+
+		other, duplicate := files[locale]
+		if duplicate {
+			return nil, fmt.Errorf("locale %s has two catalogs, %s and %s", locale, other, pth)
+		}
+		files[locale] = pth
+
+		data, err := getContent()
+		if err != nil {
+			return nil, fmt.Errorf("could not get content of %s: %w", pth, err)
+		}
+
+		var m map[string]message
+		switch path.Ext(pth) {
+		case ".json":
+			m, err = parseJSON(data)
+		case ".po":
+			m, err = parsePO(tag, data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid catalog %s: %w", pth, err)
+		}
+
+		messages[locale] = m
+	}
+
+	defaultLocale := "en"
+	if opts.DefaultLocale != "" {
+		tag, err := language.Parse(opts.DefaultLocale)
+		if err != nil {
+			return nil, fmt.Errorf("invalid default locale: %w", err)
+		}
+		defaultLocale = tag.String()
+	}
+
+	return newCatalog(defaultLocale, messages)
+}
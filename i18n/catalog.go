@@ -0,0 +1,215 @@
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+)
+
+// Catalog holds the translations of all locales.
+type Catalog struct {
+	defaultLocale string
+	messages      map[string]map[string]message
+	tags          map[string]language.Tag
+	locales       []string
+	matcher       language.Matcher
+}
+
+func newCatalog(defaultLocale string, messages map[string]map[string]message) (*Catalog, error) {
+	c := &Catalog{
+		defaultLocale: defaultLocale,
+		messages:      messages,
+		tags:          map[string]language.Tag{},
+	}
+
+	for locale := range messages {
+		c.locales = append(c.locales, locale)
+	}
+	sort.Strings(c.locales)
+
+	if len(c.locales) > 0 {
+		_, found := messages[defaultLocale]
+		if !found {
+			return nil, fmt.Errorf("there is no catalog for the default locale %s", defaultLocale)
+		}
+	}
+
+	// the default locale is matched when nothing else is
+	tags := []language.Tag{language.Make(defaultLocale)}
+	for _, locale := range c.locales {
+		tag := language.Make(locale)
+		c.tags[locale] = tag
+		if locale != defaultLocale {
+			tags = append(tags, tag)
+		}
+	}
+	c.tags[defaultLocale] = tags[0]
+	c.matcher = language.NewMatcher(tags)
+
+	return c, nil
+}
+
+// DefaultLocale returns the locale used when no other one can be negotiated.
+func (c *Catalog) DefaultLocale() string {
+	return c.defaultLocale
+}
+
+// Locales returns the locales with a catalog.
+func (c *Catalog) Locales() []string {
+	return append([]string{}, c.locales...)
+}
+
+// match returns the supported locale closest to the given ones, and whether one was found.
+func (c *Catalog) match(tags ...language.Tag) (string, bool) {
+	if len(tags) == 0 {
+		return "", false
+	}
+
+	_, index, confidence := c.matcher.Match(tags...)
+	if confidence == language.No {
+		return "", false
+	}
+
+	if index == 0 {
+		return c.defaultLocale, true
+	}
+
+	// index 0 is the default locale, the others follow in order
+	others := []string{}
+	for _, locale := range c.locales {
+		if locale != c.defaultLocale {
+			others = append(others, locale)
+		}
+	}
+	return others[index-1], true
+}
+
+// fallbacks returns the locales a translation is looked up in, e.g. de-AT, de and the default locale.
+func (c *Catalog) fallbacks(locale string) []string {
+	res := []string{}
+	seen := map[string]bool{}
+	add := func(l string) {
+		_, found := c.messages[l]
+		if found && !seen[l] {
+			seen[l] = true
+			res = append(res, l)
+		}
+	}
+
+	add(locale)
+	tag, err := language.Parse(locale)
+	if err == nil {
+		base, _ := tag.Base()
+		add(base.String())
+	}
+	add(c.defaultLocale)
+
+	return res
+}
+
+var placeholderRegexp = regexp.MustCompile(`\{([a-zA-Z_][a-zA-Z0-9_.]*)\}`)
+
+// Translate returns the message of the key in the locale, falling back to the base language
+// and the default locale, and to the key itself if there is no translation. `{name}` placeholders
+// are replaced by args, the plural form is chosen by the `count` argument.
+func (c *Catalog) Translate(locale, key string, args map[string]any) (string, error) {
+	return c.translate(locale, key, func(name string) (any, bool) {
+		v, found := args[name]
+		return v, found
+	})
+}
+
+func (c *Catalog) translate(locale, key string, arg func(name string) (any, bool)) (string, error) {
+	for _, l := range c.fallbacks(locale) {
+		m, found := c.messages[l][key]
+		if !found {
+			continue
+		}
+
+		text, hasText := m[plural.Other]
+
+		count, hasCount := arg("count")
+		if hasCount && len(m) > 1 {
+			n, err := number(count)
+			if err != nil {
+				return "", fmt.Errorf("could not translate %s: %w", key, err)
+			}
+			form, found := m[pluralForm(c.tags[l], n)]
+			if found {
+				text, hasText = form, true
+			}
+		}
+
+		// the message has no form for the count, another locale might have one
+		if !hasText {
+			continue
+		}
+
+		return placeholderRegexp.ReplaceAllStringFunc(text, func(placeholder string) string {
+			v, found := arg(placeholder[1 : len(placeholder)-1])
+			if !found {
+				return placeholder
+			}
+			return fmt.Sprint(v)
+		}), nil
+	}
+
+	return key, nil
+}
+
+// Missing returns the keys missing per locale, that are translated in another locale
+// and are not translated in the base language of the locale either.
+func (c *Catalog) Missing() map[string][]string {
+	keys := map[string]bool{}
+	for _, messages := range c.messages {
+		for k := range messages {
+			keys[k] = true
+		}
+	}
+
+	res := map[string][]string{}
+	for _, locale := range c.locales {
+		fallbacks := c.fallbacks(locale)
+		// the default locale is the last resort, not a translation
+		if len(fallbacks) > 1 && fallbacks[len(fallbacks)-1] == c.defaultLocale && locale != c.defaultLocale {
+			fallbacks = fallbacks[:len(fallbacks)-1]
+		}
+
+		for k := range keys {
+			translated := false
+			for _, l := range fallbacks {
+				_, found := c.messages[l][k]
+				if found {
+					translated = true
+					break
+				}
+			}
+			if !translated {
+				res[locale] = append(res[locale], k)
+			}
+		}
+		sort.Strings(res[locale])
+	}
+
+	return res
+}
+
+type localeKey struct{}
+
+// WithLocale returns a context carrying the locale.
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// Locale returns the locale of the context, or the default locale if it doesn't carry one.
+func (c *Catalog) Locale(ctx context.Context) string {
+	locale, found := ctx.Value(localeKey{}).(string)
+	if !found {
+		return c.defaultLocale
+	}
+	return locale
+}
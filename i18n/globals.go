@@ -0,0 +1,120 @@
+package i18n
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/draganm/go-lean/common/globals"
+	"github.com/flosch/pongo2/v6"
+)
+
+// T returns the `t(key, args)` global, translating to the locale of the context,
+// which is the negotiated locale in handlers and the default locale elsewhere.
+func (c *Catalog) T() func(ctx context.Context, key string, args map[string]any) (string, error) {
+	return func(ctx context.Context, key string, args map[string]any) (string, error) {
+		return c.Translate(c.Locale(ctx), key, args)
+	}
+}
+
+// Provider provides the `i18n` global, with the locale of the context, the supported locales
+// and `translate(locale, key, args)`, e.g. for emails sent to users by cron jobs.
+func (c *Catalog) Provider() func(ctx context.Context) globals.Values {
+	return func(ctx context.Context) globals.Values {
+		return globals.Values{
+			"locale":    c.Locale(ctx),
+			"locales":   c.Locales(),
+			"translate": c.Translate,
+		}
+	}
+}
+
+// MustacheHelpers returns the `t` lambda, `{{#t}}cart.items{{/t}}` translates the key
+// to the locale of the context. Placeholders are looked up in the data of the template.
+func (c *Catalog) MustacheHelpers(ctx context.Context) map[string]any {
+	locale := c.Locale(ctx)
+
+	return map[string]any{
+		"t": func(text string, render func(string) (string, error)) (string, error) {
+			var renderErr error
+			res, err := c.translate(locale, text, func(name string) (any, bool) {
+				v, err := render("{{" + name + "}}")
+				if err != nil {
+					renderErr = err
+					return nil, false
+				}
+				return v, v != ""
+			})
+			if renderErr != nil {
+				return "", renderErr
+			}
+			return res, err
+		},
+	}
+}
+
+// Locale is the `locale` of pongo2 templates, it is rendered as the tag of the locale,
+// e.g. `{{ locale }}`, and carries the catalog to the `t` filter.
+type Locale struct {
+	Tag     string
+	catalog *Catalog
+}
+
+func (l Locale) String() string {
+	return l.Tag
+}
+
+// Pongo2Helpers returns the `locale` of the context and the `t(key, args)` function,
+// e.g. `{{ t("cart.items", cart) }}`.
+func (c *Catalog) Pongo2Helpers(ctx context.Context) map[string]any {
+	locale := c.Locale(ctx)
+
+	return map[string]any{
+		"locale": Locale{Tag: locale, catalog: c},
+		"t": func(key string, args ...map[string]any) (string, error) {
+			merged := map[string]any{}
+			for _, a := range args {
+				for k, v := range a {
+					merged[k] = v
+				}
+			}
+			return c.Translate(locale, key, merged)
+		},
+	}
+}
+
+// Pongo2Filter is the `t` filter, translating the value to the locale of the render,
+// e.g. `{{ "cart.title"|t:locale }}`. pongo2 filters are registered for the whole process
+// and don't see the context of the render, so the locale is passed as the parameter.
+func Pongo2Filter(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	locale, isLocale := param.Interface().(Locale)
+	if !isLocale {
+		err := errors.New(`the locale of the render has to be passed, e.g. {{ "key"|t:locale }}`)
+		return nil, &pongo2.Error{Sender: "filter:t", OrigError: err}
+	}
+
+	res, err := locale.catalog.Translate(locale.Tag, in.String(), nil)
+	if err != nil {
+		return nil, &pongo2.Error{Sender: "filter:t", OrigError: err}
+	}
+
+	return pongo2.AsValue(res), nil
+}
+
+// CheckError reports the keys missing per locale.
+type CheckError struct {
+	Missing map[string][]string
+}
+
+func (e *CheckError) Error() string {
+	return fmt.Sprintf("translations are missing: %v", e.Missing)
+}
+
+// Check returns a CheckError if translations are missing.
+func (c *Catalog) Check() error {
+	missing := c.Missing()
+	if len(missing) == 0 {
+		return nil
+	}
+	return &CheckError{Missing: missing}
+}
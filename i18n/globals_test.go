@@ -0,0 +1,28 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/flosch/pongo2/v6"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/feature/plural"
+)
+
+func TestPongo2Filter(t *testing.T) {
+	c, err := newCatalog("en", map[string]map[string]message{
+		"en": {"title": {plural.Other: "Cart"}},
+		"de": {"title": {plural.Other: "Warenkorb"}},
+	})
+	require.NoError(t, err)
+
+	t.Run("translates to the locale passed as parameter", func(t *testing.T) {
+		res, err := Pongo2Filter(pongo2.AsValue("title"), pongo2.AsValue(Locale{Tag: "de", catalog: c}))
+		require.Nil(t, err)
+		require.Equal(t, "Warenkorb", res.String())
+	})
+
+	t.Run("needs the locale of the render", func(t *testing.T) {
+		_, err := Pongo2Filter(pongo2.AsValue("title"), pongo2.AsValue("de"))
+		require.ErrorContains(t, err, `the locale of the render has to be passed`)
+	})
+}
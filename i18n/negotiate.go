@@ -0,0 +1,84 @@
+package i18n
+
+import (
+	"net/http"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// Options configure catalogs and locale negotiation.
+type Options struct {
+	// DefaultLocale is used when no other locale can be negotiated, defaults to `en`.
+	DefaultLocale string
+
+	// Cookie is the name of the cookie selecting the locale, negotiated after the path prefix.
+	Cookie string
+
+	// PathPrefix negotiates the locale from the first segment of the request path,
+	// e.g. `/de/cart`, which is removed before routing.
+	PathPrefix bool
+
+	// Check makes lean.Construct fail if a locale misses translations
+	// of keys that are translated in another locale, instead of logging them.
+	Check bool
+}
+
+// Negotiate returns the locale of the request and the path without the locale prefix.
+// The locale is taken from the path prefix, the cookie and Accept-Language, in that order.
+func (c *Catalog) Negotiate(r *http.Request, opts Options) (string, string) {
+	pth := r.URL.Path
+
+	if opts.PathPrefix {
+		segment, rest, _ := strings.Cut(strings.TrimPrefix(pth, "/"), "/")
+		_, supported := c.messages[segment]
+		if supported {
+			return segment, "/" + rest
+		}
+	}
+
+	if opts.Cookie != "" {
+		cookie, err := r.Cookie(opts.Cookie)
+		if err == nil {
+			tag, err := language.Parse(cookie.Value)
+			if err == nil {
+				locale, found := c.match(tag)
+				if found {
+					return locale, pth
+				}
+			}
+		}
+	}
+
+	tags, _, err := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	if err == nil {
+		locale, found := c.match(tags...)
+		if found {
+			return locale, pth
+		}
+	}
+
+	return c.defaultLocale, pth
+}
+
+// Middleware sets the negotiated locale on the request context,
+// removing the locale prefix from the path.
+func (c *Catalog) Middleware(opts Options) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			locale, pth := c.Negotiate(r, opts)
+			r = r.WithContext(WithLocale(r.Context(), locale))
+			if pth != r.URL.Path {
+				u := *r.URL
+				u.Path = pth
+				u.RawPath = ""
+				r.URL = &u
+			}
+			w.Header().Add("Vary", "Accept-Language")
+			if opts.Cookie != "" {
+				w.Header().Add("Vary", "Cookie")
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package i18n
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+)
+
+// message is a translation, messages without plural forms only have plural.Other.
+type message map[plural.Form]string
+
+// parseJSON parses a catalog of nested objects, keys of nested objects are joined with `.`.
+// Objects whose keys are all plural categories, including `other`, are plural forms:
+//
+//	{"cart": {"title": "Cart", "items": {"one": "{count} item", "other": "{count} items"}}}
+func parseJSON(data []byte) (map[string]message, error) {
+	raw := map[string]any{}
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse json: %w", err)
+	}
+
+	res := map[string]message{}
+	err = flatten("", raw, res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func flatten(prefix string, obj map[string]any, res map[string]message) error {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		key := prefix + k
+
+		switch v := obj[k].(type) {
+		case string:
+			res[key] = message{plural.Other: v}
+		case map[string]any:
+			m, isPlural, err := pluralMessage(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			if isPlural {
+				res[key] = m
+				continue
+			}
+			err = flatten(key+".", v, res)
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%s: translations must be strings or objects", key)
+		}
+	}
+
+	return nil
+}
+
+func pluralMessage(obj map[string]any) (message, bool, error) {
+	_, hasOther := obj["other"]
+	if !hasOther {
+		return nil, false, nil
+	}
+
+	m := message{}
+	for k, v := range obj {
+		form, isForm := formNames[k]
+		if !isForm {
+			return nil, false, nil
+		}
+		s, isString := v.(string)
+		if !isString {
+			return nil, false, fmt.Errorf("plural form %s must be a string", k)
+		}
+		m[form] = s
+	}
+
+	return m, true, nil
+}
+
+type poEntry struct {
+	id     string
+	plural bool
+	strs   map[int]string
+	fuzzy  bool
+}
+
+func (e *poEntry) appender(n int) func(s string) {
+	return func(s string) { e.strs[n] += s }
+}
+
+// pluralIndexes returns the msgstr[n] index of every plural category the language uses for integers.
+// They are derived from the Plural-Forms header of the catalog if it has one, otherwise the categories
+// are in the CLDR order, e.g. one and other in English.
+func pluralIndexes(tag language.Tag, header string) (map[plural.Form]int, int, error) {
+	for _, line := range strings.Split(header, "\n") {
+		k, v, found := strings.Cut(line, ":")
+		if !found || !strings.EqualFold(strings.TrimSpace(k), "Plural-Forms") {
+			continue
+		}
+
+		nplurals, rule, err := parsePluralForms(v)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid Plural-Forms header: %w", err)
+		}
+
+		indexes, err := formIndexes(tag, nplurals, rule)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid Plural-Forms header: %w", err)
+		}
+
+		return indexes, nplurals, nil
+	}
+
+	integer := integerForms(tag)
+	indexes := map[plural.Form]int{}
+	for i, f := range integer {
+		indexes[f] = i
+	}
+	return indexes, len(integer), nil
+}
+
+// message returns the translations of the entry, the last plural form is also used for
+// plural.Other if the language only uses it for fractions, so that there is always a translation.
+func (e *poEntry) message(tag language.Tag, indexes map[plural.Form]int, nplurals int) (message, error) {
+	m := message{}
+
+	if !e.plural {
+		if e.strs[0] != "" {
+			m[plural.Other] = e.strs[0]
+		}
+		return m, nil
+	}
+
+	for n := range e.strs {
+		if n >= nplurals {
+			return nil, fmt.Errorf("%s: msgstr[%d] exceeds the %d plural forms of %s", e.id, n, nplurals, tag)
+		}
+	}
+
+	for form, n := range indexes {
+		if e.strs[n] != "" {
+			m[form] = e.strs[n]
+		}
+	}
+
+	_, hasOther := m[plural.Other]
+	last := e.strs[nplurals-1]
+	if !hasOther && last != "" {
+		m[plural.Other] = last
+	}
+
+	return m, nil
+}
+
+// parsePO parses a gettext catalog. msgid is used as the key, msgstr[n] are assigned
+// to the plural categories the language uses for integers, see pluralIndexes.
+// Untranslated and fuzzy entries are left out.
+func parsePO(tag language.Tag, data []byte) (map[string]message, error) {
+	entries := []*poEntry{}
+	header := ""
+
+	var current *poEntry
+
+	// continuation lines are appended to the last string
+	var appendTo func(s string)
+	lineNumber := 0
+
+	finish := func() {
+		switch {
+		case current == nil || current.fuzzy:
+		case current.id == "":
+			header = current.strs[0]
+		default:
+			entries = append(entries, current)
+		}
+	}
+
+	start := func() *poEntry {
+		return &poEntry{strs: map[int]string{}}
+	}
+
+	fuzzy := false
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(sc.Text())
+
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "#,"):
+			fuzzy = strings.Contains(line, "fuzzy")
+			continue
+		case strings.HasPrefix(line, "#"):
+			continue
+		}
+
+		keyword, value, _ := strings.Cut(line, " ")
+		if strings.HasPrefix(line, `"`) {
+			keyword, value = "", line
+		}
+
+		s, err := strconv.Unquote(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid string %s", lineNumber, value)
+		}
+
+		switch {
+		case keyword == "":
+			if appendTo == nil {
+				return nil, fmt.Errorf("line %d: string without a keyword", lineNumber)
+			}
+			appendTo(s)
+			continue
+		case keyword == "msgctxt":
+			return nil, fmt.Errorf("line %d: msgctxt is not supported", lineNumber)
+		case keyword == "msgid":
+			finish()
+			current = start()
+			current.fuzzy = fuzzy
+			fuzzy = false
+			current.id = s
+			e := current
+			appendTo = func(s string) { e.id += s }
+		case current == nil:
+			return nil, fmt.Errorf("line %d: %s before msgid", lineNumber, keyword)
+		case keyword == "msgid_plural":
+			current.plural = true
+			appendTo = func(string) {}
+		case keyword == "msgstr":
+			current.strs[0] = s
+			appendTo = current.appender(0)
+		case strings.HasPrefix(keyword, "msgstr[") && strings.HasSuffix(keyword, "]"):
+			n, err := strconv.Atoi(keyword[len("msgstr[") : len(keyword)-1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid keyword %s", lineNumber, keyword)
+			}
+			current.strs[n] = s
+			appendTo = current.appender(n)
+		default:
+			return nil, fmt.Errorf("line %d: unknown keyword %s", lineNumber, keyword)
+		}
+	}
+
+	err := sc.Err()
+	if err != nil {
+		return nil, fmt.Errorf("could not read po: %w", err)
+	}
+
+	finish()
+
+	indexes, nplurals, err := pluralIndexes(tag, header)
+	if err != nil {
+		return nil, err
+	}
+
+	res := map[string]message{}
+	for _, e := range entries {
+		m, err := e.message(tag, indexes, nplurals)
+		if err != nil {
+			return nil, err
+		}
+		if len(m) > 0 {
+			res[e.id] = m
+		}
+	}
+
+	return res, nil
+}
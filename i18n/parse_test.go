@@ -0,0 +1,150 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+)
+
+func TestParseJSON(t *testing.T) {
+	t.Run("nested keys are joined and plural objects are forms", func(t *testing.T) {
+		m, err := parseJSON([]byte(`{"a": {"b": "B", "c": {"one": "1", "other": "n"}, "d": {"one": "x"}}}`))
+		require.NoError(t, err)
+		require.Equal(t, map[string]message{
+			"a.b":     {plural.Other: "B"},
+			"a.c":     {plural.One: "1", plural.Other: "n"},
+			"a.d.one": {plural.Other: "x"},
+		}, m)
+	})
+
+	t.Run("translations must be strings", func(t *testing.T) {
+		_, err := parseJSON([]byte(`{"a": {"b": 1}}`))
+		require.EqualError(t, err, "a.b: translations must be strings or objects")
+	})
+}
+
+func TestParsePO(t *testing.T) {
+	t.Run("plural forms follow the categories of the language", func(t *testing.T) {
+		m, err := parsePO(language.Russian, []byte(`
+# comment
+msgid "files"
+msgid_plural "files"
+msgstr[0] "{count} файл"
+msgstr[1] "{count} файла"
+msgstr[2] "{count} "
+"файлов"
+
+#, fuzzy
+msgid "draft"
+msgstr "черновик"
+
+msgid "untranslated"
+msgstr ""
+`))
+		require.NoError(t, err)
+		require.Equal(t, map[string]message{
+			"files": {plural.One: "{count} файл", plural.Few: "{count} файла", plural.Many: "{count} файлов", plural.Other: "{count} файлов"},
+		}, m)
+	})
+
+	t.Run("the last form is used for counts without a category of their own", func(t *testing.T) {
+		m, err := parsePO(language.Polish, []byte(`
+msgid "items"
+msgid_plural "items"
+msgstr[0] "{count} przedmiot"
+msgstr[1] "{count} przedmioty"
+msgstr[2] "{count} przedmiotów"
+`))
+		require.NoError(t, err)
+
+		c, err := newCatalog("pl", map[string]map[string]message{"pl": m})
+		require.NoError(t, err)
+
+		for count, expected := range map[any]string{nil: "{count} przedmiotów", 1: "1 przedmiot", 3: "3 przedmioty", 1.5: "1.5 przedmiotów"} {
+			args := map[string]any{}
+			if count != nil {
+				args["count"] = count
+			}
+			res, err := c.Translate("pl", "items", args)
+			require.NoError(t, err)
+			require.Equal(t, expected, res)
+		}
+	})
+
+	t.Run("plural forms are mapped by the Plural-Forms header", func(t *testing.T) {
+		// the forms are ordered one, other, zero, CLDR orders them zero, one, other
+		m, err := parsePO(language.Latvian, []byte(`
+msgid ""
+msgstr ""
+"Language: lv\n"
+"Plural-Forms: nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : (n%10==0 || (n%100>=11 && n%100<=19)) ? 2 : 1);\n"
+
+msgid "files"
+msgid_plural "files"
+msgstr[0] "{count} fails"
+msgstr[1] "{count} faili"
+msgstr[2] "nav failu"
+`))
+		require.NoError(t, err)
+		require.Equal(t, map[string]message{
+			"files": {plural.One: "{count} fails", plural.Other: "{count} faili", plural.Zero: "nav failu"},
+		}, m)
+	})
+
+	t.Run("Plural-Forms headers have to match the categories of the language", func(t *testing.T) {
+		_, err := parsePO(language.English, []byte(`
+msgid ""
+msgstr "Plural-Forms: nplurals=3; plural=n == 1 ? 0 : n == 2 ? 1 : 2;\n"
+`))
+		require.EqualError(t, err, "invalid Plural-Forms header: plural forms 2 and 1 are both used for the other category of en, e.g. for 2")
+
+		// only 0 has the zero form, CLDR uses zero for 10 to 20 as well
+		_, err = parsePO(language.Latvian, []byte(`
+msgid ""
+msgstr "Plural-Forms: nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n != 0 ? 1 : 2);\n"
+`))
+		require.EqualError(t, err, "invalid Plural-Forms header: plural forms 2 and 1 are both used for the zero category of lv, e.g. for 10")
+
+		_, err = parsePO(language.English, []byte(`
+msgid ""
+msgstr "Plural-Forms: nplurals=2; plural=(n != 1;\n"
+`))
+		require.EqualError(t, err, "invalid Plural-Forms header: invalid plural expression (n != 1: missing )")
+	})
+
+	t.Run("more translations than plural forms", func(t *testing.T) {
+		_, err := parsePO(language.English, []byte("msgid \"a\"\nmsgid_plural \"a\"\nmsgstr[0] \"1\"\nmsgstr[1] \"2\"\nmsgstr[2] \"3\"\n"))
+		require.EqualError(t, err, "a: msgstr[2] exceeds the 2 plural forms of en")
+	})
+}
+
+func TestTranslate(t *testing.T) {
+	c, err := newCatalog("en", map[string]map[string]message{
+		"en":    {"a": {plural.Other: "A {x}"}, "b": {plural.Other: "B"}},
+		"de":    {"a": {plural.Other: "DE {x}"}},
+		"de-AT": {},
+	})
+	require.NoError(t, err)
+
+	t.Run("falls back to the base language and the default locale", func(t *testing.T) {
+		res, err := c.Translate("de-AT", "a", map[string]any{"x": 1})
+		require.NoError(t, err)
+		require.Equal(t, "DE 1", res)
+
+		res, err = c.Translate("de-AT", "b", nil)
+		require.NoError(t, err)
+		require.Equal(t, "B", res)
+	})
+
+	t.Run("missing keys are returned as they are", func(t *testing.T) {
+		res, err := c.Translate("de", "c", nil)
+		require.NoError(t, err)
+		require.Equal(t, "c", res)
+	})
+
+	t.Run("keys are missing unless the locale or its base language translate them", func(t *testing.T) {
+		require.Equal(t, map[string][]string{"de": {"b"}, "de-AT": {"b"}}, c.Missing())
+	})
+}
@@ -0,0 +1,94 @@
+package i18n
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+)
+
+// forms are the plural categories in the order used by CLDR and gettext.
+var forms = []plural.Form{plural.Zero, plural.One, plural.Two, plural.Few, plural.Many, plural.Other}
+
+var formNames = map[string]plural.Form{
+	"zero":  plural.Zero,
+	"one":   plural.One,
+	"two":   plural.Two,
+	"few":   plural.Few,
+	"many":  plural.Many,
+	"other": plural.Other,
+}
+
+// formName returns the name of the plural category, e.g. `few`.
+func formName(form plural.Form) string {
+	for name, f := range formNames {
+		if f == form {
+			return name
+		}
+	}
+	return fmt.Sprint(int(form))
+}
+
+// pluralForm returns the CLDR plural category of n in the language.
+func pluralForm(tag language.Tag, n float64) plural.Form {
+	s := strconv.FormatFloat(math.Abs(n), 'f', -1, 64)
+	integer, fraction, _ := strings.Cut(s, ".")
+
+	i, err := strconv.Atoi(integer)
+	if err != nil {
+		// too large to have a category other than other
+		return plural.Other
+	}
+
+	f, _ := strconv.Atoi(fraction)
+	trimmed := strings.TrimRight(fraction, "0")
+	t, _ := strconv.Atoi(trimmed)
+
+	return plural.Cardinal.MatchPlural(tag, i, len(fraction), len(trimmed), f, t)
+}
+
+// integerForms returns the plural categories the language uses for integers,
+// in the order of the msgstr[n] translations of gettext catalogs.
+func integerForms(tag language.Tag) []plural.Form {
+	used := map[plural.Form]bool{}
+	for i := 0; i < 1000; i++ {
+		used[plural.Cardinal.MatchPlural(tag, i, 0, 0, 0, 0)] = true
+	}
+
+	res := []plural.Form{}
+	for _, f := range forms {
+		if used[f] {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+// number converts the count passed from JavaScript or Go.
+func number(v any) (float64, error) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), nil
+	case int32:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	case uint:
+		return float64(n), nil
+	case uint32:
+		return float64(n), nil
+	case uint64:
+		return float64(n), nil
+	case float32:
+		return float64(n), nil
+	case float64:
+		return n, nil
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(n), 64)
+	default:
+		return 0, fmt.Errorf("count must be a number, not %T", v)
+	}
+}
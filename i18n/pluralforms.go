@@ -0,0 +1,286 @@
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+)
+
+// pluralRule is the `plural` expression of a gettext Plural-Forms header,
+// returning the index of the msgstr[n] used for n.
+type pluralRule func(n int) int
+
+// parsePluralForms parses a Plural-Forms header value,
+// e.g. `nplurals=2; plural=(n != 1);`.
+func parsePluralForms(header string) (int, pluralRule, error) {
+	nplurals := -1
+	var expr string
+
+	for _, part := range strings.Split(header, ";") {
+		k, v, found := strings.Cut(part, "=")
+		if !found {
+			continue
+		}
+
+		switch strings.TrimSpace(k) {
+		case "nplurals":
+			n, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil || n < 1 {
+				return 0, nil, fmt.Errorf("invalid nplurals %s", strings.TrimSpace(v))
+			}
+			nplurals = n
+		case "plural":
+			expr = v
+		}
+	}
+
+	if nplurals == -1 || expr == "" {
+		return 0, nil, fmt.Errorf("nplurals and plural have to be set")
+	}
+
+	p := &ruleParser{src: expr}
+	eval, err := p.parse()
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid plural expression %s: %w", strings.TrimSpace(expr), err)
+	}
+
+	return nplurals, pluralRule(eval), nil
+}
+
+// formIndexes returns the msgstr[n] index of every plural category the language uses for integers,
+// derived by applying the rule to integers. Categories that would need more than one index are
+// an error, the rule doesn't match the plural rules of the language.
+func formIndexes(tag language.Tag, nplurals int, rule pluralRule) (map[plural.Form]int, error) {
+	res := map[plural.Form]int{}
+	for n := 0; n < 1000; n++ {
+		form := plural.Cardinal.MatchPlural(tag, n, 0, 0, 0, 0)
+		index := rule(n)
+
+		if index < 0 || index >= nplurals {
+			return nil, fmt.Errorf("plural form %d of %d exceeds nplurals=%d", index, n, nplurals)
+		}
+
+		previous, found := res[form]
+		if found && previous != index {
+			return nil, fmt.Errorf("plural forms %d and %d are both used for the %s category of %s, e.g. for %d", previous, index, formName(form), tag, n)
+		}
+		res[form] = index
+	}
+	return res, nil
+}
+
+// ruleParser parses the C expressions of Plural-Forms headers, using n, integers,
+// the ternary, logical, comparison and arithmetic operators and parentheses.
+type ruleParser struct {
+	src string
+	pos int
+}
+
+type ruleExpr func(n int) int
+
+func (p *ruleParser) parse() (ruleExpr, error) {
+	e, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpace()
+	if p.pos != len(p.src) {
+		return nil, fmt.Errorf("unexpected %q", p.src[p.pos:])
+	}
+
+	return e, nil
+}
+
+func (p *ruleParser) skipSpace() {
+	for p.pos < len(p.src) && strings.ContainsRune(" \t\r\n", rune(p.src[p.pos])) {
+		p.pos++
+	}
+}
+
+// accept consumes the operator if it is next.
+func (p *ruleParser) accept(op string) bool {
+	p.skipSpace()
+	if !strings.HasPrefix(p.src[p.pos:], op) {
+		return false
+	}
+
+	// don't take the prefix of a longer operator, e.g. < of <=
+	rest := p.src[p.pos+len(op):]
+	if (op == "<" || op == ">" || op == "!" || op == "=") && strings.HasPrefix(rest, "=") {
+		return false
+	}
+
+	p.pos += len(op)
+	return true
+}
+
+func boolean(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (p *ruleParser) ternary() (ruleExpr, error) {
+	cond, err := p.binary(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.accept("?") {
+		return cond, nil
+	}
+
+	then, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.accept(":") {
+		return nil, errors.New("missing : of ?")
+	}
+
+	otherwise, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(n int) int {
+		if cond(n) != 0 {
+			return then(n)
+		}
+		return otherwise(n)
+	}, nil
+}
+
+// binaryOperators by increasing precedence.
+var binaryOperators = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<=", ">=", "<", ">"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+// applyOperator applies the binary operator, division by zero is undefined in C and evaluates to 0.
+func applyOperator(op string, a, b int) int {
+	switch op {
+	case "||":
+		return boolean(a != 0 || b != 0)
+	case "&&":
+		return boolean(a != 0 && b != 0)
+	case "==":
+		return boolean(a == b)
+	case "!=":
+		return boolean(a != b)
+	case "<=":
+		return boolean(a <= b)
+	case ">=":
+		return boolean(a >= b)
+	case "<":
+		return boolean(a < b)
+	case ">":
+		return boolean(a > b)
+	case "+":
+		return a + b
+	case "-":
+		return a - b
+	case "*":
+		return a * b
+	case "/":
+		if b == 0 {
+			return 0
+		}
+		return a / b
+	default:
+		if b == 0 {
+			return 0
+		}
+		return a % b
+	}
+}
+
+func (p *ruleParser) binary(level int) (ruleExpr, error) {
+	if level == len(binaryOperators) {
+		return p.unary()
+	}
+
+	left, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op := ""
+		for _, candidate := range binaryOperators[level] {
+			if p.accept(candidate) {
+				op = candidate
+				break
+			}
+		}
+
+		if op == "" {
+			return left, nil
+		}
+
+		right, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		l := left
+		left = func(n int) int {
+			return applyOperator(op, l(n), right(n))
+		}
+	}
+}
+
+func (p *ruleParser) unary() (ruleExpr, error) {
+	if p.accept("!") {
+		e, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(n int) int { return boolean(e(n) == 0) }, nil
+	}
+
+	if p.accept("(") {
+		e, err := p.ternary()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, errors.New("missing )")
+		}
+		return e, nil
+	}
+
+	if p.accept("n") {
+		return func(n int) int { return n }, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+		p.pos++
+	}
+
+	if start == p.pos {
+		if p.pos == len(p.src) {
+			return nil, fmt.Errorf("unexpected end")
+		}
+		return nil, fmt.Errorf("unexpected %q at %d", p.src[p.pos], p.pos)
+	}
+
+	v, err := strconv.Atoi(p.src[start:p.pos])
+	if err != nil {
+		return nil, err
+	}
+
+	return func(int) int { return v }, nil
+}
@@ -0,0 +1,73 @@
+package lean_test
+
+import (
+	"context"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/draganm/go-lean"
+	"github.com/draganm/go-lean/i18n"
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestI18n(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sfs, err := fs.Sub(simple, "fixtures/i18n")
+	require.NoError(t, err)
+
+	w, err := lean.Construct(ctx, sfs, testr.New(t), map[string]any{}, lean.WithI18n(i18n.Options{
+		Cookie:     "lang",
+		PathPrefix: true,
+	}))
+	require.NoError(t, err)
+
+	body := func(t *testing.T, req *http.Request) string {
+		rec := httptest.NewRecorder()
+		w.ServeHTTP(rec, req)
+		require.Equal(t, 200, rec.Code, rec.Body.String())
+		return rec.Body.String()
+	}
+
+	t.Run("default locale", func(t *testing.T) {
+		require.Equal(t, "en: Hello Ana! 1 item, 2 items, 5 items", body(t, httptest.NewRequest("GET", "/greet", nil)))
+	})
+
+	t.Run("locale is negotiated from Accept-Language", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/greet", nil)
+		req.Header.Set("Accept-Language", "fr;q=0.9, de-AT;q=0.8")
+		require.Equal(t, "de: Hallo Ana! 1 Artikel, 2 Artikel, 5 Artikel", body(t, req))
+	})
+
+	t.Run("cookie takes precedence over Accept-Language", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/greet", nil)
+		req.Header.Set("Accept-Language", "de")
+		req.AddCookie(&http.Cookie{Name: "lang", Value: "pl"})
+		require.Equal(t, "pl: Cześć Ana! 1 przedmiot, 2 przedmioty, 5 przedmiotów", body(t, req))
+	})
+
+	t.Run("path prefix takes precedence and is removed before routing", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/de/greet", nil)
+		req.AddCookie(&http.Cookie{Name: "lang", Value: "pl"})
+		require.Equal(t, "de: Hallo Ana! 1 Artikel, 2 Artikel, 5 Artikel", body(t, req))
+	})
+
+	t.Run("mustache lambda takes placeholders from the data", func(t *testing.T) {
+		require.Equal(t, "Hallo &lt;Ana&gt;! 1 Artikel", body(t, httptest.NewRequest("GET", "/de/mustache", nil)))
+	})
+
+	t.Run("pongo2 t function and filter translate to the locale of the render", func(t *testing.T) {
+		require.Equal(t, "3 Artikel Made with lean de", body(t, httptest.NewRequest("GET", "/de/pongo", nil)))
+	})
+
+	t.Run("check mode reports missing keys per locale", func(t *testing.T) {
+		_, err := lean.Construct(ctx, sfs, testr.New(t), map[string]any{}, lean.WithI18n(i18n.Options{Check: true}))
+		checkErr := &i18n.CheckError{}
+		require.ErrorAs(t, err, &checkErr)
+		require.Equal(t, map[string][]string{"de": {"footer"}}, checkErr.Missing)
+	})
+}
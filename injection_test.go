@@ -0,0 +1,61 @@
+package lean_test
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/draganm/go-lean"
+	"github.com/draganm/go-lean/common/globals"
+	"github.com/draganm/go-lean/cron"
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInjection(t *testing.T) {
+	sfs, err := fs.Sub(simple, "fixtures/injection")
+	require.NoError(t, err)
+
+	t.Run("globals are only available in contexts they can be injected in", func(t *testing.T) {
+		require := require.New(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		reported := make(chan string, 10)
+
+		w, err := lean.Construct(ctx, sfs, testr.New(t), map[string]any{
+			"jobName": func(name cron.JobName) string {
+				return string(name)
+			},
+			"report": func(s string) {
+				reported <- s
+			},
+		})
+		require.NoError(err)
+
+		require.HTTPBodyContains(w.ServeHTTP, "GET", "/job", nil, "undefined")
+
+		select {
+		case name := <-reported:
+			require.Equal("/cron/named.js", name)
+		case <-time.After(3 * time.Second):
+			require.Fail("cron job has not reported")
+		}
+	})
+
+	t.Run("parameters that can't be injected are reported", func(t *testing.T) {
+		require := require.New(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		_, err := lean.Construct(ctx, sfs, testr.New(t), map[string]any{
+			"db": func(dsn string) globals.Values {
+				return nil
+			},
+		})
+		require.ErrorContains(err, "global db: parameter 1 (string) can't be injected in any context")
+	})
+}
@@ -0,0 +1,66 @@
+package lean_test
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+
+	"github.com/draganm/go-lean"
+	"github.com/draganm/go-lean/web/jshandler"
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsolation(t *testing.T) {
+	sfs, err := fs.Sub(simple, "fixtures/isolation")
+	require.NoError(t, err)
+
+	t.Run("restore", func(t *testing.T) {
+		require := require.New(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		w, err := lean.Construct(ctx, sfs, testr.New(t), map[string]any{}, lean.WithIsolation(jshandler.IsolationRestore))
+		require.NoError(err)
+
+		for i := 0; i < 3; i++ {
+			require.HTTPBodyContains(w.ServeHTTP, "GET", "/counter", nil, `{"count":1,"leaked":1}`)
+		}
+
+		for i := 0; i < 3; i++ {
+			require.HTTPBodyContains(w.ServeHTTP, "GET", "/module", nil, "1")
+		}
+	})
+
+	t.Run("freeze", func(t *testing.T) {
+		require := require.New(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		w, err := lean.Construct(ctx, sfs, testr.New(t), map[string]any{}, lean.WithIsolation(jshandler.IsolationFreeze))
+		require.NoError(err)
+
+		require.HTTPBodyContains(w.ServeHTTP, "GET", "/push", nil, "frozen: TypeError")
+		require.HTTPBodyContains(w.ServeHTTP, "GET", "/registry", nil, "frozen: TypeError")
+
+		for i := 0; i < 3; i++ {
+			require.HTTPBodyContains(w.ServeHTTP, "GET", "/counter", nil, `{"count":1,"leaked":1}`)
+		}
+	})
+
+	t.Run("mutation log", func(t *testing.T) {
+		require := require.New(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		lc := &logCollector{}
+		w, err := lean.Construct(ctx, sfs, lc.logger(), map[string]any{}, lean.WithGlobalMutationLog())
+		require.NoError(err)
+
+		require.HTTPStatusCode(w.ServeHTTP, "GET", "/counter", nil, 200)
+		require.Contains(lc.String(), `"msg"="handler mutated global state" "method"="GET" "handlerPath"="/counter" "mutations"=["added leaked","reassigned count"]`)
+	})
+}
@@ -0,0 +1,59 @@
+package lean_test
+
+import (
+	"context"
+	"io/fs"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/draganm/go-lean"
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMustacheLayouts(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sfs, err := fs.Sub(simple, "fixtures/layouts")
+	require.NoError(t, err)
+
+	w, err := lean.Construct(ctx, sfs, testr.New(t), map[string]any{})
+	require.NoError(t, err)
+
+	body := func(t *testing.T, target string) string {
+		rec := httptest.NewRecorder()
+		w.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		require.Equal(t, 200, rec.Code, rec.Body.String())
+		return rec.Body.String()
+	}
+
+	t.Run("nearest layout is used by default", func(t *testing.T) {
+		require := require.New(t)
+		require.Equal("<html><title>Page</title><body>page lean</body></html>", body(t, "/page"))
+		require.Equal("<admin>users admin</admin>", body(t, "/admin/users"))
+	})
+
+	t.Run("layout can be chosen", func(t *testing.T) {
+		require := require.New(t)
+		require.Equal("<minimal>plain lean</minimal>", body(t, "/plain?layout=/layouts/minimal"))
+		require.Equal("<admin>plain lean</admin>", body(t, "/plain?layout=../admin/_layout"))
+	})
+
+	t.Run("layout can be disabled", func(t *testing.T) {
+		require := require.New(t)
+		require.Equal("plain lean", body(t, "/plain?layout=none"))
+	})
+
+	t.Run("strings are rendered in layouts", func(t *testing.T) {
+		require := require.New(t)
+		require.Equal("<minimal>string lean</minimal> string lean", body(t, "/string"))
+	})
+
+	t.Run("missing layouts are reported", func(t *testing.T) {
+		require := require.New(t)
+		rec := httptest.NewRecorder()
+		w.ServeHTTP(rec, httptest.NewRequest("GET", "/plain?layout=missing", nil))
+		require.Equal(500, rec.Code)
+	})
+}
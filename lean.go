@@ -3,21 +3,40 @@ package lean
 import (
 	"context"
 	"fmt"
+	"html/template"
 	"io"
 	"io/fs"
+	"net/http"
 
+	"github.com/dop251/goja"
 	"github.com/draganm/go-lean/common/globals"
+	"github.com/draganm/go-lean/common/goja/fieldmapper"
+	"github.com/draganm/go-lean/common/jslog"
+	"github.com/draganm/go-lean/common/jsruntime"
+	"github.com/draganm/go-lean/common/nodecompat"
 	"github.com/draganm/go-lean/cron"
+	"github.com/draganm/go-lean/gotemplate"
+	"github.com/draganm/go-lean/i18n"
+	"github.com/draganm/go-lean/mail"
+	"github.com/draganm/go-lean/markdown"
 	"github.com/draganm/go-lean/metrics"
 	"github.com/draganm/go-lean/mustache"
 	"github.com/draganm/go-lean/pongo2"
 	"github.com/draganm/go-lean/require"
+	"github.com/draganm/go-lean/shared"
 	"github.com/draganm/go-lean/web"
+	"github.com/draganm/go-lean/web/jshandler"
+	"github.com/draganm/go-lean/web/types"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-logr/logr"
 )
 
-func Construct(ctx context.Context, src fs.FS, log logr.Logger, globs map[string]any) (*chi.Mux, error) {
+func Construct(ctx context.Context, src fs.FS, log logr.Logger, globs map[string]any, opts ...Option) (*chi.Mux, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
 	files := map[string](func() ([]byte, error)){}
 
 	err := fs.WalkDir(src, ".", func(pth string, d fs.DirEntry, err error) error {
@@ -46,6 +65,26 @@ func Construct(ctx context.Context, src fs.FS, log logr.Logger, globs map[string
 		return nil, fmt.Errorf("could not read the lean fs: %w", err)
 	}
 
+	if o.handlerOptions.DevMode {
+		// files are consumed by builders, error pages need the sources until the end
+		sources := map[string](func() ([]byte, error)){}
+		for k, v := range files {
+			sources[k] = v
+		}
+
+		o.handlerOptions.SourceLookup = func(fileName string) (string, bool) {
+			getContent, found := sources[fileName]
+			if !found {
+				return "", false
+			}
+			data, err := getContent()
+			if err != nil {
+				return "", false
+			}
+			return string(data), true
+		}
+	}
+
 	consumeFiles := func(fn func(string, func() ([]byte, error)) bool) {
 
 		consumed := []string{}
@@ -68,13 +107,19 @@ func Construct(ctx context.Context, src fs.FS, log logr.Logger, globs map[string
 	requireBuilder := require.NewBuilder()
 	mustacheBuilder := mustache.NewBuilder()
 	pongo2Builder := pongo2.NewBuilder()
+	gotemplateBuilder := gotemplate.NewBuilder()
+	markdownBuilder := markdown.NewBuilder()
+	i18nBuilder := i18n.NewBuilder()
 
 	cc := chainedConsume{
+		i18nBuilder.Consume,
 		pongo2Builder.Consume,
 		metricsBuilder.Consume,
 		cronBuilder.Consume,
 		requireBuilder.Consume,
 		mustacheBuilder.Consume,
+		gotemplateBuilder.Consume,
+		markdownBuilder.Consume,
 		// web builder has always to be the last
 		// since it will serve any unclaimed file
 		// under '/web' as static file
@@ -83,22 +128,139 @@ func Construct(ctx context.Context, src fs.FS, log logr.Logger, globs map[string
 
 	consumeFiles(cc.Consume)
 
-	req := requireBuilder.Build()
+	// globals are set once all providers have been created
+	factory := &jsruntime.Factory{
+		FieldMapper: fieldmapper.FallbackFieldMapper{CamelCase: o.camelCase},
+		Injectables: injectables(),
+		Limits:      o.limits,
+	}
+
+	if o.nodeCompat != nil {
+		factory.Init = append(factory.Init, func(rt *goja.Runtime) error {
+			return nodecompat.Enable(rt, *o.nodeCompat)
+		})
+	}
+
+	req, err := requireBuilder.Build()
+	if err != nil {
+		return nil, fmt.Errorf("could not build require provider: %w", err)
+	}
+
+	catalog, err := i18nBuilder.Create(o.i18n)
+	if err != nil {
+		return nil, fmt.Errorf("could not load translations: %w", err)
+	}
+
+	if o.i18n.Check {
+		err = catalog.Check()
+		if err != nil {
+			return nil, fmt.Errorf("invalid translations: %w", err)
+		}
+	}
+
+	for locale, keys := range catalog.Missing() {
+		log.Info("translations are missing", "locale", locale, "keys", keys)
+	}
+
+	// translations are only set up when there are catalogs,
+	// so that applications without them can use the names
+	translating := len(catalog.Locales()) > 0
+
+	mustacheOpts := mustache.Options{}
+	pongo2Opts := pongo2.Options{
+		Tags:   o.pongo2Tags,
+		Limits: o.limits,
+		NewRuntime: func() (*goja.Runtime, error) {
+			rt, _, err := factory.New()
+			return rt, err
+		},
+	}
+
+	if translating {
+		mustacheOpts.Helpers = catalog.MustacheHelpers
+		pongo2Opts.Helpers = catalog.Pongo2Helpers
+	}
+
+	_, defined := o.pongo2Filters["t"]
+	if translating && !defined {
+		WithPongo2Filter("t", i18n.Pongo2Filter)(o)
+	}
 
-	mst, err := mustacheBuilder.Create()
+	pongo2Opts.Filters = o.pongo2Filters
+
+	mst, err := mustacheBuilder.Create(mustacheOpts)
 	if err != nil {
 		return nil, fmt.Errorf("could not build mustache provider: %w", err)
 	}
 
-	pongo2, err := pongo2Builder.Create()
+	pongo2Provider, err := pongo2Builder.Create(pongo2Opts)
 	if err != nil {
 		return nil, fmt.Errorf("could not build pongo2 provider: %w", err)
 	}
 
+	store := o.sharedStore
+	if store == nil {
+		store = shared.New(ctx)
+	}
+
+	gotemplateProvider, err := gotemplateBuilder.Create(o.templateFuncs)
+	if err != nil {
+		return nil, fmt.Errorf("could not build gotemplate provider: %w", err)
+	}
+
+	// globals of all contexts, builders add their own extras
 	finalGlobs := globals.Globals{
 		"require":  req,
-		"mustache": mst,
-		"pongo2":   pongo2,
+		"markdown": markdown.Provider,
+		"log":      jslog.Provider(o.logLevel),
+	}
+
+	if translating {
+		finalGlobs["t"] = catalog.T()
+		finalGlobs["i18n"] = catalog.Provider()
+	}
+
+	if o.mail != nil {
+		mailOpts := *o.mail
+		if mailOpts.Templates == nil {
+			mailOpts.Templates = map[string]mail.Render{
+				".mustache": func(ctx context.Context, name string, data any) (string, error) {
+					return mst.RenderToString(ctx, types.StandaloneHandlerPath, name, data)
+				},
+				".pongo2": func(ctx context.Context, name string, data any) (string, error) {
+					vals, isMap := data.(map[string]any)
+					if data != nil && !isMap {
+						return "", fmt.Errorf("data of pongo2 templates must be an object")
+					}
+					return pongo2Provider.RenderToString(ctx, types.StandaloneHandlerPath, name, vals)
+				},
+				".gohtml": func(ctx context.Context, name string, data any) (string, error) {
+					return gotemplateProvider.RenderToString(ctx, types.StandaloneHandlerPath, name, data)
+				},
+			}
+		}
+
+		mailer, err := mail.New(mailOpts)
+		if err != nil {
+			return nil, fmt.Errorf("could not create mailer: %w", err)
+		}
+
+		finalGlobs["mail"] = mailer.Provider()
+	}
+
+	finalGlobs, err = finalGlobs.Merge(store.Globals())
+	if err != nil {
+		return nil, fmt.Errorf("could not merge shared store globals: %w", err)
+	}
+
+	modules, err := globals.Modules(o.modules...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modules: %w", err)
+	}
+
+	finalGlobs, err = finalGlobs.Merge(modules)
+	if err != nil {
+		return nil, fmt.Errorf("could not merge modules: %w", err)
 	}
 
 	finalGlobs, err = finalGlobs.Merge(globs)
@@ -106,19 +268,80 @@ func Construct(ctx context.Context, src fs.FS, log logr.Logger, globs map[string
 		return nil, fmt.Errorf("could not merge globals: %w", err)
 	}
 
-	mux, err := webBuilder.Create(log, finalGlobs)
+	if o.nodeCompat != nil {
+		finalGlobs, err = finalGlobs.Merge(nodecompat.Globals())
+		if err != nil {
+			return nil, fmt.Errorf("could not merge node compatibility globals: %w", err)
+		}
+	}
+
+	factory.Globals = finalGlobs
+
+	// handlers render templates to the response, other contexts only to strings
+	standaloneTemplates := globals.Globals{
+		"mustache":   mst.Standalone(),
+		"pongo2":     pongo2Provider.Standalone(),
+		"gotemplate": gotemplateProvider.Standalone(),
+	}
+
+	factory.Contexts = map[string]globals.Globals{
+		"web": {
+			"mustache":   mst,
+			"pongo2":     pongo2Provider,
+			"gotemplate": gotemplateProvider,
+		},
+		"cron":    standaloneTemplates,
+		"metrics": standaloneTemplates,
+	}
+
+	middlewares := []func(http.Handler) http.Handler{}
+	if translating {
+		middlewares = append(middlewares, catalog.Middleware(o.i18n))
+	}
+
+	mux, err := webBuilder.Create(log, factory, o.handlerOptions, middlewares...)
 	if err != nil {
 		return nil, fmt.Errorf("could not create web hadlder: %w", err)
 	}
 
-	cronBuilder.Start(ctx, log, finalGlobs)
+	err = markdownBuilder.Register(log, mux, map[string]markdown.Layout{
+		".mustache": func(ctx context.Context, pagePath, name string, data map[string]any) (string, error) {
+			return mst.RenderToString(ctx, types.HandlerPath(pagePath), name, data)
+		},
+		".pongo2": func(ctx context.Context, pagePath, name string, data map[string]any) (string, error) {
+			return pongo2Provider.RenderToString(ctx, types.HandlerPath(pagePath), name, data)
+		},
+		".gohtml": func(ctx context.Context, pagePath, name string, data map[string]any) (string, error) {
+			data["content"] = template.HTML(data["content"].(string))
+			return gotemplateProvider.RenderToString(ctx, types.HandlerPath(pagePath), name, data)
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not serve markdown pages: %w", err)
+	}
+
+	err = cronBuilder.Start(ctx, log, factory)
+	if err != nil {
+		return nil, fmt.Errorf("could not start cron jobs: %w", err)
+	}
 
-	metricsBuilder.Start(ctx, log, globs)
+	err = metricsBuilder.Start(ctx, log, factory)
+	if err != nil {
+		return nil, fmt.Errorf("could not start metrics: %w", err)
+	}
 
 	return mux, nil
 
 }
 
+func injectables() globals.Injectables {
+	return globals.Injectables{
+		"web":     jshandler.InjectableTypes,
+		"cron":    cron.InjectableTypes,
+		"metrics": metrics.InjectableTypes,
+	}
+}
+
 type chainedConsume []func(string, func() ([]byte, error)) bool
 
 func (cc chainedConsume) Consume(pth string, getContent func() ([]byte, error)) bool {
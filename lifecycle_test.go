@@ -0,0 +1,62 @@
+package lean_test
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/draganm/go-lean"
+	"github.com/draganm/go-lean/common/globals"
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderFinish(t *testing.T) {
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sfs, err := fs.Sub(simple, "fixtures/lifecycle")
+	require.NoError(err)
+
+	mu := &sync.Mutex{}
+	events := []string{}
+	record := func(e string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	}
+
+	// a per-request transaction committed on success and rolled back on failure
+	txProvider := func(r *http.Request) (globals.Values, func(error)) {
+		record("begin " + r.URL.Path)
+		return globals.Values{
+			"exec": func(stmt string) {
+				record(stmt)
+			},
+		}, func(err error) {
+			if err != nil {
+				record(fmt.Sprintf("rollback %s", r.URL.Path))
+				return
+			}
+			record(fmt.Sprintf("commit %s", r.URL.Path))
+		}
+	}
+
+	w, err := lean.Construct(ctx, sfs, testr.New(t), map[string]any{"tx": txProvider})
+	require.NoError(err)
+
+	require.HTTPStatusCode(w.ServeHTTP, "POST", "/ok", nil, 200)
+	require.HTTPStatusCode(w.ServeHTTP, "POST", "/fail", nil, 500)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal([]string{
+		"begin /ok", "insert", "commit /ok",
+		"begin /fail", "insert", "rollback /fail",
+	}, events)
+}
@@ -0,0 +1,98 @@
+package lean_test
+
+import (
+	"context"
+	"io/fs"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/draganm/go-lean"
+	"github.com/draganm/go-lean/common/limits"
+	"github.com/go-logr/logr"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+func violationCount(t *testing.T, limit, script string) float64 {
+	for _, m := range findMetrics(t, "lean_resource_limit_violations_count", dto.MetricType_COUNTER) {
+		labels := map[string]string{}
+		for _, l := range m.GetLabel() {
+			labels[l.GetName()] = l.GetValue()
+		}
+		if labels["limit"] == limit && labels["script"] == script {
+			return m.GetCounter().GetValue()
+		}
+	}
+	return 0
+}
+
+func TestLimits(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sfs, err := fs.Sub(simple, "fixtures/limits")
+	require.NoError(t, err)
+
+	// metrics are collected when they are unregistered after the test as well
+	w, err := lean.Construct(ctx, sfs, logr.Discard(), map[string]any{}, lean.WithLimits(limits.Limits{
+		MaxCallStackSize: 100,
+		TimeBudget:       100 * time.Millisecond,
+		MaxResponseBytes: 100,
+	}))
+	require.NoError(t, err)
+
+	t.Run("call stack", func(t *testing.T) {
+		require := require.New(t)
+
+		before := violationCount(t, "call_stack", "/web/recurse/@GET.js")
+		require.HTTPStatusCode(w.ServeHTTP, "GET", "/recurse", nil, 500)
+		require.Equal(before+1, violationCount(t, "call_stack", "/web/recurse/@GET.js"))
+	})
+
+	t.Run("time budget of a busy handler", func(t *testing.T) {
+		require := require.New(t)
+
+		before := violationCount(t, "time_budget", "/web/spin/@GET.js")
+		require.HTTPStatusCode(w.ServeHTTP, "GET", "/spin", nil, 503)
+		require.HTTPBodyContains(w.ServeHTTP, "GET", "/spin", nil, "handler exceeded its time budget")
+		require.Equal(before+2, violationCount(t, "time_budget", "/web/spin/@GET.js"))
+	})
+
+	t.Run("time budget of a waiting handler", func(t *testing.T) {
+		require := require.New(t)
+
+		before := violationCount(t, "time_budget", "/web/wait/@GET.js")
+		start := time.Now()
+		require.HTTPStatusCode(w.ServeHTTP, "GET", "/wait", nil, 503)
+		require.Less(time.Since(start), 5*time.Second)
+		require.Equal(before+1, violationCount(t, "time_budget", "/web/wait/@GET.js"))
+	})
+
+	t.Run("response size", func(t *testing.T) {
+		require := require.New(t)
+
+		before := violationCount(t, "response_size", "/web/large/@GET.js")
+		rec := httptest.NewRecorder()
+		w.ServeHTTP(rec, httptest.NewRequest("GET", "/large", nil))
+		require.Equal(200, rec.Code)
+		require.Equal("small", rec.Body.String())
+		require.Equal(before+1, violationCount(t, "response_size", "/web/large/@GET.js"))
+	})
+
+	t.Run("caught response size error", func(t *testing.T) {
+		require := require.New(t)
+
+		before := violationCount(t, "response_size", "/web/caught/@GET.js")
+		require.HTTPStatusCode(w.ServeHTTP, "GET", "/caught", nil, 500)
+		require.Equal(before+1, violationCount(t, "response_size", "/web/caught/@GET.js"))
+	})
+
+	t.Run("metric time budget", func(t *testing.T) {
+		require := require.New(t)
+
+		require.Empty(findMetrics(t, "spinning", dto.MetricType_GAUGE))
+		// metrics are collected on registration as well
+		require.GreaterOrEqual(violationCount(t, "time_budget", "/metrics/spinning.gauge.js"), 1.0)
+	})
+}
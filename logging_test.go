@@ -0,0 +1,116 @@
+package lean_test
+
+import (
+	"context"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/draganm/go-lean"
+	"github.com/draganm/go-lean/common/jslog"
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type logCollector struct {
+	mu     sync.Mutex
+	logged []string
+}
+
+func (lc *logCollector) logger() logr.Logger {
+	return funcr.New(func(prefix, args string) {
+		lc.mu.Lock()
+		defer lc.mu.Unlock()
+		lc.logged = append(lc.logged, prefix+" "+args)
+	}, funcr.Options{Verbosity: 1})
+}
+
+func (lc *logCollector) String() string {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	return strings.Join(lc.logged, "\n")
+}
+
+func TestLogging(t *testing.T) {
+	sfs, err := fs.Sub(simple, "fixtures/logging")
+	require.NoError(t, err)
+
+	traceID, err := trace.TraceIDFromHex("0102030405060708090a0b0c0d0e0f10")
+	require.NoError(t, err)
+	spanID, err := trace.SpanIDFromHex("0102030405060708")
+	require.NoError(t, err)
+
+	tracedRequest := func() *http.Request {
+		sc := trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    traceID,
+			SpanID:     spanID,
+			TraceFlags: trace.FlagsSampled,
+		})
+		req := httptest.NewRequest("GET", "/log", nil)
+		return req.WithContext(trace.ContextWithSpanContext(req.Context(), sc))
+	}
+
+	t.Run("all levels", func(t *testing.T) {
+		require := require.New(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		lc := &logCollector{}
+		w, err := lean.Construct(ctx, sfs, lc.logger(), map[string]any{})
+		require.NoError(err)
+
+		rec := httptest.NewRecorder()
+		w.ServeHTTP(rec, tracedRequest())
+		require.Equal(200, rec.Code)
+
+		logged := lc.String()
+		require.Contains(logged, `"level"=1 "msg"="debug message" "method"="GET" "handlerPath"="/log"`)
+		require.Contains(logged, `"traceID"="0102030405060708090a0b0c0d0e0f10" "spanID"="0102030405060708"`)
+		require.Contains(logged, `"msg"="user created"`)
+		require.Contains(logged, `"user.id"=42 "user.name"="jane"`)
+		require.Contains(logged, `"msg"="slow request"`)
+		require.Contains(logged, `"level"="warn" "duration"=1200`)
+		require.Contains(logged, `"msg"="could not notify" "error"="Error: mailer down at handler (/web/log/@GET.js:5:`)
+		require.Contains(logged, `"attempt"=3`)
+		require.Contains(logged, `"msg"="logr style"`)
+		require.Contains(logged, `"bar"="baz"`)
+		require.Contains(logged, `orders "level"=1 "msg"="derived logger"`)
+		require.Contains(logged, `"order"={"id":7}`)
+		require.Contains(logged, `"msg"="enabled"`)
+		require.Contains(logged, `"default"=true "verbose"=false`)
+
+		require.Eventually(func() bool {
+			return strings.Contains(lc.String(), `"msg"="cron tick" "cronJob"="/cron/logging.js"`)
+		}, 3*time.Second, 50*time.Millisecond)
+		require.Contains(lc.String(), `"count"=1`)
+	})
+
+	t.Run("minimum level", func(t *testing.T) {
+		require := require.New(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		lc := &logCollector{}
+		w, err := lean.Construct(ctx, sfs, lc.logger(), map[string]any{}, lean.WithLogLevel(jslog.LevelWarn))
+		require.NoError(err)
+
+		rec := httptest.NewRecorder()
+		w.ServeHTTP(rec, tracedRequest())
+		require.Equal(200, rec.Code)
+
+		logged := lc.String()
+		require.NotContains(logged, "debug message")
+		require.NotContains(logged, "user created")
+		require.NotContains(logged, "logr style")
+		require.Contains(logged, "slow request")
+		require.Contains(logged, "could not notify")
+	})
+}
@@ -0,0 +1,169 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageBytes(t *testing.T) {
+	m := &Message{
+		ID:          "1@example.com",
+		From:        "app@example.com",
+		To:          []string{"Alice <alice@example.com>"},
+		Bcc:         []string{"audit@example.com"},
+		Subject:     "Grüße",
+		Text:        "Hello",
+		HTML:        "<p>Hello</p>",
+		Attachments: []Attachment{{Filename: "a.txt", Content: []byte("attached")}},
+		Date:        time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	data, err := m.Bytes()
+	require.NoError(t, err)
+
+	parsed, err := mail.ReadMessage(strings.NewReader(string(data)))
+	require.NoError(t, err)
+	require.Equal(t, "<1@example.com>", parsed.Header.Get("Message-Id"))
+	require.Empty(t, parsed.Header.Get("Bcc"))
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	require.NoError(t, err)
+	require.Equal(t, "Grüße", subject)
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/mixed", mediaType)
+
+	mr := multipart.NewReader(parsed.Body, params["boundary"])
+
+	body, err := mr.NextPart()
+	require.NoError(t, err)
+	mediaType, _, err = mime.ParseMediaType(body.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/alternative", mediaType)
+
+	attachment, err := mr.NextPart()
+	require.NoError(t, err)
+	require.Equal(t, "a.txt", attachment.FileName())
+	content, err := io.ReadAll(attachment)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "YXR0YWNoZWQ=")
+
+	_, err = mr.NextPart()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestMessageHeaders(t *testing.T) {
+	msg := func(headers map[string]string) *Message {
+		return &Message{From: "app@example.com", To: []string{"alice@example.com"}, Text: "Hello", Headers: headers}
+	}
+
+	data, err := msg(map[string]string{"x-campaign": "spring\r\nBcc: evil@example.com"}).Bytes()
+	require.NoError(t, err)
+	parsed, err := mail.ReadMessage(strings.NewReader(string(data)))
+	require.NoError(t, err)
+	require.Empty(t, parsed.Header.Get("Bcc"))
+	require.Len(t, parsed.Header["X-Campaign"], 1)
+
+	for name, err := range map[string]string{
+		"X-Campaign\r\nBcc: evil@example.com": `invalid header name "X-Campaign\r\nBcc: evil@example.com"`,
+		"X Campaign":                          `invalid header name "X Campaign"`,
+		"from":                                "header from is set from the message and can't be set in headers",
+		"Content-Type":                        "header Content-Type is set from the message and can't be set in headers",
+		"mime-version":                        "header mime-version is set from the message and can't be set in headers",
+	} {
+		_, berr := msg(map[string]string{name: "spoof@example.com"}).Bytes()
+		require.EqualError(t, berr, err)
+		require.EqualError(t, msg(map[string]string{name: "spoof@example.com"}).Validate(), err)
+	}
+}
+
+type failingTransport struct {
+	errs  []error
+	calls int
+}
+
+func (t *failingTransport) Send(ctx context.Context, m *Message) error {
+	t.calls++
+	if len(t.errs) == 0 {
+		return nil
+	}
+	err := t.errs[0]
+	t.errs = t.errs[1:]
+	return err
+}
+
+func TestMailerSend(t *testing.T) {
+	msg := func() *Message {
+		return &Message{To: []string{"alice@example.com"}, Subject: "Hi", Text: "Hello"}
+	}
+
+	t.Run("failed deliveries are retried", func(t *testing.T) {
+		tr := &failingTransport{errs: []error{errors.New("busy"), errors.New("busy")}}
+		ml, err := New(Options{Transport: tr, From: "app@example.com", Backoff: time.Millisecond})
+		require.NoError(t, err)
+
+		id, err := ml.Send(context.Background(), msg())
+		require.NoError(t, err)
+		require.True(t, strings.HasSuffix(id, "@example.com"))
+		require.Equal(t, 3, tr.calls)
+	})
+
+	t.Run("delivery fails after the last attempt", func(t *testing.T) {
+		tr := &failingTransport{errs: []error{errors.New("busy"), errors.New("busy")}}
+		ml, err := New(Options{Transport: tr, From: "app@example.com", Attempts: 2, Backoff: time.Millisecond})
+		require.NoError(t, err)
+
+		_, err = ml.Send(context.Background(), msg())
+		require.ErrorContains(t, err, "busy")
+		require.Equal(t, 2, tr.calls)
+	})
+
+	t.Run("permanent errors are not retried", func(t *testing.T) {
+		tr := &failingTransport{errs: []error{Permanent(errors.New("no such user"))}}
+		ml, err := New(Options{Transport: tr, From: "app@example.com", Backoff: time.Millisecond})
+		require.NoError(t, err)
+
+		_, err = ml.Send(context.Background(), msg())
+		require.True(t, IsPermanent(err))
+		require.Equal(t, 1, tr.calls)
+	})
+
+	t.Run("messages without a sender are invalid", func(t *testing.T) {
+		ml, err := New(Options{Transport: NewMemoryTransport()})
+		require.NoError(t, err)
+
+		_, err = ml.Send(context.Background(), msg())
+		require.ErrorContains(t, err, "invalid message")
+	})
+}
+
+func TestSpoolTransport(t *testing.T) {
+	dir := t.TempDir()
+
+	ml, err := New(Options{Transport: NewSpoolTransport(dir), From: "app@example.com"})
+	require.NoError(t, err)
+
+	id, err := ml.Send(context.Background(), &Message{To: []string{"alice@example.com"}, Bcc: []string{"audit@example.com"}, Subject: "Hi", Text: "Hello"})
+	require.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	data, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	require.Contains(t, string(data), "Bcc: <audit@example.com>")
+	require.Contains(t, string(data), id)
+}
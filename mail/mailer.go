@@ -0,0 +1,172 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	deliveryDuration = promauto.NewSummary(prometheus.SummaryOpts{
+		Name: "leanmail_delivery_duration",
+		Help: "Duration of delivering a message, including retries",
+	})
+
+	sentCount = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "leanmail_sent_count",
+		Help: "Number of messages delivered",
+	})
+
+	failedCount = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "leanmail_failed_count",
+		Help: "Number of messages that could not be delivered",
+	})
+
+	retryCount = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "leanmail_retry_count",
+		Help: "Number of delivery attempts that have been retried",
+	})
+)
+
+var tracer = otel.Tracer("github.com/draganm/go-lean/leanweb/mail")
+
+// Render renders a template, used for bodies rendered from templates.
+type Render func(ctx context.Context, name string, data any) (string, error)
+
+// Options configure delivery of messages.
+type Options struct {
+	// Transport delivers the messages.
+	Transport Transport
+
+	// From is the sender of messages that don't set one.
+	From string
+
+	// Attempts is the number of times delivery is attempted, defaults to 3.
+	// Permanent errors are not retried.
+	Attempts int
+
+	// Backoff is the delay before the first retry, doubled for each further one. Defaults to a second.
+	Backoff time.Duration
+
+	// Templates render bodies, keyed by the extension of the template, e.g. `.mustache`.
+	Templates map[string]Render
+}
+
+// Mailer sends messages through the transport, retrying failed deliveries.
+type Mailer struct {
+	opts Options
+}
+
+func New(opts Options) (*Mailer, error) {
+	if opts.Transport == nil {
+		return nil, errors.New("mail transport is not set")
+	}
+
+	if opts.Attempts <= 0 {
+		opts.Attempts = 3
+	}
+
+	if opts.Backoff <= 0 {
+		opts.Backoff = time.Second
+	}
+
+	return &Mailer{opts: opts}, nil
+}
+
+// Send delivers the message and returns its Message-ID. The sender
+// defaults to Options.From, the ID and date are set if they are missing.
+func (ml *Mailer) Send(ctx context.Context, m *Message) (string, error) {
+	if m.From == "" {
+		m.From = ml.opts.From
+	}
+
+	err := m.Validate()
+	if err != nil {
+		return "", fmt.Errorf("invalid message: %w", err)
+	}
+
+	if m.ID == "" {
+		m.ID = newID(m.From)
+	}
+
+	if m.Date.IsZero() {
+		m.Date = time.Now()
+	}
+
+	ctx, span := tracer.Start(ctx, "mail.Send",
+		trace.WithAttributes(
+			attribute.String("message_id", m.ID),
+		),
+	)
+	defer span.End()
+
+	start := time.Now()
+	defer func() {
+		deliveryDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	backoff := ml.opts.Backoff
+
+	for attempt := 1; ; attempt++ {
+		err = ml.opts.Transport.Send(ctx, m)
+		if err == nil {
+			sentCount.Inc()
+			return m.ID, nil
+		}
+
+		if IsPermanent(err) || attempt == ml.opts.Attempts {
+			break
+		}
+
+		retryCount.Inc()
+
+		waitErr := wait(ctx, backoff)
+		if waitErr != nil {
+			err = errors.Join(err, waitErr)
+			break
+		}
+
+		backoff *= 2
+	}
+
+	failedCount.Inc()
+	err = fmt.Errorf("could not send message: %w", err)
+	span.RecordError(err)
+	return "", err
+}
+
+func wait(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// render renders the body template, selecting the engine by the extension of the name.
+func (ml *Mailer) render(ctx context.Context, name string, data any) (string, error) {
+	ext := path.Ext(name)
+	render, found := ml.opts.Templates[ext]
+	if !found {
+		return "", fmt.Errorf("could not render %s: unsupported template type %s", name, ext)
+	}
+
+	res, err := render(ctx, name[:len(name)-len(ext)], data)
+	if err != nil {
+		return "", fmt.Errorf("could not render %s: %w", name, err)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,355 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Attachment is a file attached to a message.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
+// Message is an email with a text and/or HTML body.
+type Message struct {
+	// ID is the Message-ID without angle brackets, generated when the message is sent.
+	ID          string
+	From        string
+	To          []string
+	Cc          []string
+	Bcc         []string
+	ReplyTo     string
+	Subject     string
+	Text        string
+	HTML        string
+	Headers     map[string]string
+	Attachments []Attachment
+	Date        time.Time
+}
+
+// Recipients returns the addresses the message is delivered to, including Bcc.
+func (m *Message) Recipients() ([]string, error) {
+	res := []string{}
+	for _, list := range [][]string{m.To, m.Cc, m.Bcc} {
+		for _, a := range list {
+			addr, err := mail.ParseAddress(a)
+			if err != nil {
+				return nil, fmt.Errorf("invalid recipient %q: %w", a, err)
+			}
+			res = append(res, addr.Address)
+		}
+	}
+	return res, nil
+}
+
+// Sender returns the address of the sender.
+func (m *Message) Sender() (string, error) {
+	addr, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return "", fmt.Errorf("invalid sender %q: %w", m.From, err)
+	}
+	return addr.Address, nil
+}
+
+// Validate checks that the message can be sent.
+func (m *Message) Validate() error {
+	_, err := m.Sender()
+	if err != nil {
+		return err
+	}
+
+	rcpts, err := m.Recipients()
+	if err != nil {
+		return err
+	}
+
+	if len(rcpts) == 0 {
+		return errors.New("message has no recipients")
+	}
+
+	if m.Text == "" && m.HTML == "" {
+		return errors.New("message has no body")
+	}
+
+	return m.validateHeaders()
+}
+
+// reservedHeaders are set from the fields of the message and can't be set in Headers.
+var reservedHeaders = map[string]bool{
+	"From":                      true,
+	"To":                        true,
+	"Cc":                        true,
+	"Bcc":                       true,
+	"Reply-To":                  true,
+	"Subject":                   true,
+	"Date":                      true,
+	"Message-Id":                true,
+	"Mime-Version":              true,
+	"Content-Type":              true,
+	"Content-Transfer-Encoding": true,
+}
+
+// validateHeaders checks that names of the additional headers are field names,
+// printable ASCII characters other than the colon, and not set from the fields of the message.
+func (m *Message) validateHeaders() error {
+	for k := range m.Headers {
+		if k == "" {
+			return errors.New("header name is empty")
+		}
+
+		for _, c := range k {
+			if c < '!' || c > '~' || c == ':' {
+				return fmt.Errorf("invalid header name %q", k)
+			}
+		}
+
+		if reservedHeaders[textproto.CanonicalMIMEHeaderKey(k)] {
+			return fmt.Errorf("header %s is set from the message and can't be set in headers", k)
+		}
+	}
+	return nil
+}
+
+func newID(from string) string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	domain := "localhost"
+	addr, err := mail.ParseAddress(from)
+	if err == nil {
+		_, d, found := strings.Cut(addr.Address, "@")
+		if found {
+			domain = d
+		}
+	}
+
+	return hex.EncodeToString(b) + "@" + domain
+}
+
+func formatAddresses(list []string) (string, error) {
+	res := []string{}
+	for _, a := range list {
+		addr, err := mail.ParseAddress(a)
+		if err != nil {
+			return "", fmt.Errorf("invalid address %q: %w", a, err)
+		}
+		res = append(res, addr.String())
+	}
+	return strings.Join(res, ", "), nil
+}
+
+// Bytes returns the message in MIME format, as it is sent. Bcc recipients are left out.
+func (m *Message) Bytes() ([]byte, error) {
+	return m.build(false)
+}
+
+func (m *Message) build(withBcc bool) ([]byte, error) {
+	err := m.validateHeaders()
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+
+	header := func(k, v string) {
+		fmt.Fprintf(buf, "%s: %s\r\n", k, v)
+	}
+
+	date := m.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	from, err := formatAddresses([]string{m.From})
+	if err != nil {
+		return nil, err
+	}
+
+	header("From", from)
+
+	addressHeaders := []addressHeader{{"To", m.To}, {"Cc", m.Cc}}
+	if withBcc {
+		addressHeaders = append(addressHeaders, addressHeader{"Bcc", m.Bcc})
+	}
+	if m.ReplyTo != "" {
+		addressHeaders = append(addressHeaders, addressHeader{"Reply-To", []string{m.ReplyTo}})
+	}
+
+	for _, h := range addressHeaders {
+		if len(h.list) == 0 {
+			continue
+		}
+		v, err := formatAddresses(h.list)
+		if err != nil {
+			return nil, err
+		}
+		header(h.name, v)
+	}
+
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header("Date", date.Format(time.RFC1123Z))
+	if m.ID != "" {
+		header("Message-ID", "<"+m.ID+">")
+	}
+
+	keys := make([]string, 0, len(m.Headers))
+	for k := range m.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		header(textproto.CanonicalMIMEHeaderKey(k), mime.QEncoding.Encode("utf-8", m.Headers[k]))
+	}
+
+	header("MIME-Version", "1.0")
+
+	bodyHeader, body, err := m.body()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(m.Attachments) == 0 {
+		header("Content-Type", bodyHeader.Get("Content-Type"))
+		if bodyHeader.Get("Content-Transfer-Encoding") != "" {
+			header("Content-Transfer-Encoding", bodyHeader.Get("Content-Transfer-Encoding"))
+		}
+		buf.WriteString("\r\n")
+		buf.Write(body)
+		return buf.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(buf)
+	header("Content-Type", mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": mw.Boundary()}))
+	buf.WriteString("\r\n")
+
+	pw, err := mw.CreatePart(bodyHeader)
+	if err != nil {
+		return nil, err
+	}
+	_, err = pw.Write(body)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range m.Attachments {
+		err = writeAttachment(mw, a)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = mw.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+type addressHeader struct {
+	name string
+	list []string
+}
+
+// body returns the headers and content of the body, text and HTML
+// bodies are sent as alternatives.
+func (m *Message) body() (textproto.MIMEHeader, []byte, error) {
+	buf := &bytes.Buffer{}
+	h := textproto.MIMEHeader{}
+
+	if m.Text != "" && m.HTML != "" {
+		mw := multipart.NewWriter(buf)
+		h.Set("Content-Type", mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": mw.Boundary()}))
+
+		for _, part := range []struct{ contentType, content string }{{"text/plain", m.Text}, {"text/html", m.HTML}} {
+			ph := textproto.MIMEHeader{}
+			ph.Set("Content-Type", part.contentType+"; charset=utf-8")
+			ph.Set("Content-Transfer-Encoding", "quoted-printable")
+			pw, err := mw.CreatePart(ph)
+			if err != nil {
+				return nil, nil, err
+			}
+			err = writeQuotedPrintable(pw, part.content)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		err := mw.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return h, buf.Bytes(), nil
+	}
+
+	contentType, content := "text/plain", m.Text
+	if m.HTML != "" {
+		contentType, content = "text/html", m.HTML
+	}
+
+	h.Set("Content-Type", contentType+"; charset=utf-8")
+	h.Set("Content-Transfer-Encoding", "quoted-printable")
+	err := writeQuotedPrintable(buf, content)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return h, buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(w io.Writer, s string) error {
+	qw := quotedprintable.NewWriter(w)
+	_, err := qw.Write([]byte(s))
+	if err != nil {
+		return err
+	}
+	return qw.Close()
+}
+
+func writeAttachment(mw *multipart.Writer, a Attachment) error {
+	contentType := a.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(strings.ToLower(path.Ext(a.Filename)))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", contentType)
+	h.Set("Content-Transfer-Encoding", "base64")
+	h.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename}))
+
+	pw, err := mw.CreatePart(h)
+	if err != nil {
+		return err
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(a.Content)
+	for len(encoded) > 76 {
+		_, err = io.WriteString(pw, encoded[:76]+"\r\n")
+		if err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err = io.WriteString(pw, encoded+"\r\n")
+	return err
+}
@@ -0,0 +1,142 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dop251/goja"
+	"github.com/draganm/go-lean/common/eventloop"
+	"github.com/draganm/go-lean/common/globals"
+)
+
+// SendOptions describe a message sent from JavaScript. Addresses are a string or an array of strings,
+// bodies can be rendered from templates under /templates, e.g. `emails/welcome.mustache`.
+type SendOptions struct {
+	From         string            `lean:"from"`
+	To           any               `lean:"to"`
+	Cc           any               `lean:"cc"`
+	Bcc          any               `lean:"bcc"`
+	ReplyTo      string            `lean:"replyTo"`
+	Subject      string            `lean:"subject"`
+	Text         string            `lean:"text"`
+	HTML         string            `lean:"html"`
+	TextTemplate string            `lean:"textTemplate"`
+	HTMLTemplate string            `lean:"htmlTemplate"`
+	Data         any               `lean:"data"`
+	Headers      map[string]string `lean:"headers"`
+	Attachments  []struct {
+		Filename    string `lean:"filename"`
+		ContentType string `lean:"contentType"`
+		Content     any    `lean:"content"`
+	} `lean:"attachments"`
+}
+
+func addresses(v any) ([]string, error) {
+	switch a := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{a}, nil
+	case []string:
+		return a, nil
+	case []any:
+		res := []string{}
+		for _, e := range a {
+			s, isString := e.(string)
+			if !isString {
+				return nil, fmt.Errorf("addresses must be strings, not %T", e)
+			}
+			res = append(res, s)
+		}
+		return res, nil
+	default:
+		return nil, fmt.Errorf("addresses must be a string or an array of strings, not %T", v)
+	}
+}
+
+func content(v any) ([]byte, error) {
+	switch c := v.(type) {
+	case string:
+		return []byte(c), nil
+	case []byte:
+		return c, nil
+	case goja.ArrayBuffer:
+		return c.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("attachment content must be a string or an ArrayBuffer, not %T", v)
+	}
+}
+
+// message renders the bodies and converts the options to a message.
+func (ml *Mailer) message(ctx context.Context, opts *SendOptions) (*Message, error) {
+	m := &Message{
+		From:    opts.From,
+		ReplyTo: opts.ReplyTo,
+		Subject: opts.Subject,
+		Text:    opts.Text,
+		HTML:    opts.HTML,
+		Headers: opts.Headers,
+	}
+
+	var err error
+	for _, a := range []struct {
+		v    any
+		list *[]string
+	}{{opts.To, &m.To}, {opts.Cc, &m.Cc}, {opts.Bcc, &m.Bcc}} {
+		*a.list, err = addresses(a.v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.TextTemplate != "" {
+		m.Text, err = ml.render(ctx, opts.TextTemplate, opts.Data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.HTMLTemplate != "" {
+		m.HTML, err = ml.render(ctx, opts.HTMLTemplate, opts.Data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, a := range opts.Attachments {
+		data, err := content(a.Content)
+		if err != nil {
+			return nil, fmt.Errorf("attachment %s: %w", a.Filename, err)
+		}
+		m.Attachments = append(m.Attachments, Attachment{
+			Filename:    a.Filename,
+			ContentType: a.ContentType,
+			Content:     data,
+		})
+	}
+
+	return m, nil
+}
+
+// Provider provides the `mail` global. `mail.send(options)` renders the message
+// and returns a promise of its Message-ID, resolved once it has been delivered.
+func (ml *Mailer) Provider() func(ctx context.Context, loop *eventloop.EventLoop) globals.Values {
+	return func(ctx context.Context, loop *eventloop.EventLoop) globals.Values {
+		return globals.Values{
+			"send": func(opts *SendOptions) (*goja.Promise, error) {
+				if opts == nil {
+					return nil, fmt.Errorf("message is not set")
+				}
+
+				m, err := ml.message(ctx, opts)
+				if err != nil {
+					return nil, fmt.Errorf("could not compose message: %w", err)
+				}
+
+				return loop.Promise(func() (any, error) {
+					return ml.Send(ctx, m)
+				}), nil
+			},
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+
+	"github.com/go-logr/logr"
+)
+
+// SMTPTransport delivers messages to an SMTP server, using STARTTLS when the server supports it.
+type SMTPTransport struct {
+	// Addr is the host and port of the server, e.g. `smtp.example.com:587`.
+	Addr string
+
+	// Auth authenticates with the server if set, e.g. smtp.PlainAuth.
+	Auth smtp.Auth
+
+	// TLSConfig is used for STARTTLS, defaults to verifying the host of Addr.
+	TLSConfig *tls.Config
+}
+
+func NewSMTPTransport(addr string, auth smtp.Auth) *SMTPTransport {
+	return &SMTPTransport{Addr: addr, Auth: auth}
+}
+
+// smtpError marks errors the server has replied with a 5xx code as permanent.
+func smtpError(msg string, err error) error {
+	err = fmt.Errorf("%s: %w", msg, err)
+	te := &textproto.Error{}
+	if errors.As(err, &te) && te.Code >= 500 {
+		return Permanent(err)
+	}
+	return err
+}
+
+func (t *SMTPTransport) Send(ctx context.Context, m *Message) error {
+	from, err := m.Sender()
+	if err != nil {
+		return Permanent(err)
+	}
+
+	rcpts, err := m.Recipients()
+	if err != nil {
+		return Permanent(err)
+	}
+
+	data, err := m.Bytes()
+	if err != nil {
+		return Permanent(fmt.Errorf("could not build message: %w", err))
+	}
+
+	host, _, err := net.SplitHostPort(t.Addr)
+	if err != nil {
+		return Permanent(fmt.Errorf("invalid address %s: %w", t.Addr, err))
+	}
+
+	d := &net.Dialer{}
+	conn, err := d.DialContext(ctx, "tcp", t.Addr)
+	if err != nil {
+		return fmt.Errorf("could not connect to %s: %w", t.Addr, err)
+	}
+	defer conn.Close()
+
+	deadline, hasDeadline := ctx.Deadline()
+	if hasDeadline {
+		conn.SetDeadline(deadline)
+	}
+
+	// the connection is closed when the context is done
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return fmt.Errorf("could not start smtp session: %w", err)
+	}
+	defer c.Close()
+
+	hasTLS, _ := c.Extension("STARTTLS")
+	if hasTLS {
+		cfg := t.TLSConfig
+		if cfg == nil {
+			cfg = &tls.Config{ServerName: host}
+		}
+		err = c.StartTLS(cfg)
+		if err != nil {
+			return fmt.Errorf("could not start tls: %w", err)
+		}
+	}
+
+	if t.Auth != nil {
+		err = c.Auth(t.Auth)
+		if err != nil {
+			return smtpError("could not authenticate", err)
+		}
+	}
+
+	err = c.Mail(from)
+	if err != nil {
+		return smtpError("sender rejected", err)
+	}
+
+	for _, rcpt := range rcpts {
+		err = c.Rcpt(rcpt)
+		if err != nil {
+			return smtpError(fmt.Sprintf("recipient %s rejected", rcpt), err)
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return smtpError("could not send data", err)
+	}
+
+	_, err = w.Write(data)
+	if err != nil {
+		return fmt.Errorf("could not send data: %w", err)
+	}
+
+	err = w.Close()
+	if err != nil {
+		return smtpError("message rejected", err)
+	}
+
+	// the message is delivered once the data is accepted, failing
+	// to end the session must not make the mailer send it again
+	err = c.Quit()
+	if err != nil {
+		logr.FromContextOrDiscard(ctx).Info("could not end smtp session", "error", err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package mail
+
+import (
+	"context"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTPServer accepts sessions without STARTTLS, replying to RCPT with the
+// next of rcptReplies (250 once they are used up) and to QUIT with quitReply.
+type fakeSMTPServer struct {
+	l           net.Listener
+	mu          sync.Mutex
+	rcptReplies []string
+	quitReply   string
+	sessions    int
+	messages    []string
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	s := &fakeSMTPServer{l: l, quitReply: "221 bye"}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	s.mu.Lock()
+	s.sessions++
+	s.mu.Unlock()
+
+	tc := textproto.NewConn(conn)
+	reply := func(line string) {
+		_ = tc.PrintfLine("%s", line)
+	}
+
+	reply("220 fake ESMTP")
+	for {
+		line, err := tc.ReadLine()
+		if err != nil {
+			return
+		}
+
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch cmd {
+		case "EHLO":
+			reply("250-fake")
+			reply("250 8BITMIME")
+		case "MAIL":
+			reply("250 ok")
+		case "RCPT":
+			s.mu.Lock()
+			r := "250 ok"
+			if len(s.rcptReplies) > 0 {
+				r = s.rcptReplies[0]
+				s.rcptReplies = s.rcptReplies[1:]
+			}
+			s.mu.Unlock()
+			reply(r)
+		case "DATA":
+			reply("354 go ahead")
+			data, err := tc.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, string(data))
+			s.mu.Unlock()
+			reply("250 queued")
+		case "QUIT":
+			reply(s.quitReply)
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func TestSMTPTransport(t *testing.T) {
+	msg := func() *Message {
+		return &Message{To: []string{"alice@example.com"}, Subject: "Hi", Text: "Hello"}
+	}
+
+	t.Run("messages are delivered without STARTTLS", func(t *testing.T) {
+		s := newFakeSMTPServer(t)
+		// the message is accepted before QUIT fails
+		s.quitReply = "421 closing"
+
+		ml, err := New(Options{Transport: NewSMTPTransport(s.l.Addr().String(), nil), From: "app@example.com", Backoff: time.Millisecond})
+		require.NoError(t, err)
+
+		id, err := ml.Send(context.Background(), msg())
+		require.NoError(t, err)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		require.Equal(t, 1, s.sessions)
+		require.Len(t, s.messages, 1)
+		require.Contains(t, s.messages[0], id)
+		require.Contains(t, s.messages[0], "Subject: Hi")
+	})
+
+	t.Run("rejected recipients are permanent errors", func(t *testing.T) {
+		s := newFakeSMTPServer(t)
+		s.rcptReplies = []string{"550 no such user"}
+
+		ml, err := New(Options{Transport: NewSMTPTransport(s.l.Addr().String(), nil), From: "app@example.com", Backoff: time.Millisecond})
+		require.NoError(t, err)
+
+		_, err = ml.Send(context.Background(), msg())
+		require.True(t, IsPermanent(err))
+		require.ErrorContains(t, err, "recipient alice@example.com rejected")
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		require.Equal(t, 1, s.sessions)
+		require.Empty(t, s.messages)
+	})
+
+	t.Run("temporary failures are retried", func(t *testing.T) {
+		s := newFakeSMTPServer(t)
+		s.rcptReplies = []string{"451 try again later"}
+
+		ml, err := New(Options{Transport: NewSMTPTransport(s.l.Addr().String(), nil), From: "app@example.com", Backoff: time.Millisecond})
+		require.NoError(t, err)
+
+		_, err = ml.Send(context.Background(), msg())
+		require.NoError(t, err)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		require.Equal(t, 2, s.sessions)
+		require.Len(t, s.messages, 1)
+	})
+}
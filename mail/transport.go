@@ -0,0 +1,113 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Transport delivers messages.
+type Transport interface {
+	Send(ctx context.Context, m *Message) error
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks an error of a transport as permanent, so that sending is not retried,
+// e.g. when the server rejects a recipient.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether sending failed with a permanent error.
+func IsPermanent(err error) bool {
+	pe := &permanentError{}
+	return errors.As(err, &pe)
+}
+
+// MemoryTransport keeps sent messages, e.g. for tests.
+type MemoryTransport struct {
+	mu       sync.Mutex
+	messages []*Message
+}
+
+func NewMemoryTransport() *MemoryTransport {
+	return &MemoryTransport{}
+}
+
+func (t *MemoryTransport) Send(ctx context.Context, m *Message) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.messages = append(t.messages, m)
+	return nil
+}
+
+// Messages returns the messages sent so far.
+func (t *MemoryTransport) Messages() []*Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*Message{}, t.messages...)
+}
+
+// SpoolTransport writes messages as `.eml` files into a directory, for another process to deliver.
+// Unlike sent messages, the files include the Bcc header.
+type SpoolTransport struct {
+	Dir string
+}
+
+func NewSpoolTransport(dir string) *SpoolTransport {
+	return &SpoolTransport{Dir: dir}
+}
+
+func (t *SpoolTransport) Send(ctx context.Context, m *Message) error {
+	data, err := m.build(true)
+	if err != nil {
+		return Permanent(fmt.Errorf("could not build message: %w", err))
+	}
+
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), newID("")[:16])
+
+	// files appear in the spool once they are complete
+	tmp, err := os.CreateTemp(t.Dir, ".spool-*")
+	if err != nil {
+		return fmt.Errorf("could not create spool file: %w", err)
+	}
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("could not write spool file: %w", err)
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("could not write spool file: %w", err)
+	}
+
+	err = os.Rename(tmp.Name(), filepath.Join(t.Dir, name))
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("could not move spool file: %w", err)
+	}
+
+	return nil
+}
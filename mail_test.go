@@ -0,0 +1,86 @@
+package lean_test
+
+import (
+	"context"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/draganm/go-lean"
+	"github.com/draganm/go-lean/mail"
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMail(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sfs, err := fs.Sub(simple, "fixtures/mail")
+	require.NoError(t, err)
+
+	transport := mail.NewMemoryTransport()
+
+	w, err := lean.Construct(ctx, sfs, testr.New(t), nil, lean.WithMail(mail.Options{
+		Transport: transport,
+		From:      "app@example.com",
+	}))
+	require.NoError(t, err)
+
+	find := func(subject string) *mail.Message {
+		for _, m := range transport.Messages() {
+			if m.Subject == subject {
+				return m
+			}
+		}
+		return nil
+	}
+
+	t.Run("handlers send messages rendered from templates", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		w.ServeHTTP(rec, httptest.NewRequest("POST", "/signup?name=alice", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		m := find("Welcome alice")
+		require.NotNil(t, m)
+		require.Equal(t, m.ID, rec.Body.String())
+		require.Equal(t, "app@example.com", m.From)
+		require.Equal(t, []string{"alice@example.com"}, m.To)
+		require.Equal(t, "<p>Welcome alice!</p>", m.HTML)
+		require.Equal(t, "Welcome alice!", m.Text)
+		require.Len(t, m.Attachments, 1)
+		require.Equal(t, "terms.txt", m.Attachments[0].Filename)
+		require.Equal(t, []byte("Be nice."), m.Attachments[0].Content)
+	})
+
+	t.Run("invalid messages reject the promise", func(t *testing.T) {
+		require.HTTPStatusCode(t, w.ServeHTTP, "POST", "/invalid", nil, http.StatusBadRequest)
+		require.HTTPBodyContains(t, w.ServeHTTP, "POST", "/invalid", nil, "invalid message")
+	})
+
+	t.Run("cron jobs send messages", func(t *testing.T) {
+		require.Eventually(t, func() bool {
+			return find("Report") != nil
+		}, 3*time.Second, 50*time.Millisecond)
+
+		m := find("Report")
+		require.Equal(t, []string{"ops@example.com", "dev@example.com"}, m.To)
+		require.Equal(t, []string{"audit@example.com"}, m.Bcc)
+		require.Equal(t, "<p>2 jobs</p>", m.HTML)
+	})
+}
+
+func TestMailGlobalRequiresOption(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sfs, err := fs.Sub(simple, "fixtures/mail")
+	require.NoError(t, err)
+
+	w, err := lean.Construct(ctx, sfs, testr.New(t), nil)
+	require.NoError(t, err)
+
+	require.HTTPStatusCode(t, w.ServeHTTP, "POST", "/signup?name=bob", nil, http.StatusInternalServerError)
+}
@@ -0,0 +1,176 @@
+package markdown
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Layout renders the layout template of a page, resolving its name relative to the page.
+// Data contains the front matter of the page and the rendered page as `content`.
+type Layout func(ctx context.Context, pagePath, name string, data map[string]any) (string, error)
+
+type Builder struct {
+	files map[string]func() ([]byte, error)
+}
+
+func NewBuilder() *Builder {
+	return &Builder{
+		files: map[string]func() ([]byte, error){},
+	}
+}
+
+func (b *Builder) Consume(pth string, getContent func() ([]byte, error)) bool {
+	if !strings.HasPrefix(pth, "/web") {
+		return false
+	}
+
+	pth = strings.TrimPrefix(pth, "/web")
+
+	if path.Ext(pth) != ".md" {
+		return false
+	}
+
+	b.files[pth] = getContent
+	return true
+}
+
+type page struct {
+	path        string
+	content     string
+	frontMatter map[string]any
+	layout      string
+	renderer    Layout
+}
+
+// requestPath returns the path pages are served at, `/docs/intro.md` at `/docs/intro`
+// and `/docs/index.md` at `/docs`.
+func requestPath(pth string) string {
+	pth = strings.TrimSuffix(pth, ".md")
+	if path.Base(pth) == "index" {
+		return path.Dir(pth)
+	}
+	return pth
+}
+
+func (b *Builder) page(pth string, layouts map[string]Layout) (*page, error) {
+	data, err := b.files[pth]()
+	if err != nil {
+		return nil, fmt.Errorf("could not get content of %s: %w", pth, err)
+	}
+
+	fm, src, err := splitFrontMatter(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page %s: %w", pth, err)
+	}
+
+	content, err := convert(pageRenderer, src)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page %s: %w", pth, err)
+	}
+
+	_, reserved := fm["content"]
+	if reserved {
+		return nil, fmt.Errorf("invalid page %s: content can't be set in the front matter", pth)
+	}
+
+	p := &page{
+		path:        pth,
+		content:     content,
+		frontMatter: fm,
+	}
+
+	layout := fm["layout"]
+	delete(fm, "layout")
+
+	switch l := layout.(type) {
+	case nil:
+		return p, nil
+	case bool:
+		if !l {
+			return p, nil
+		}
+	case string:
+		ext := path.Ext(l)
+		renderer, found := layouts[ext]
+		if !found {
+			return nil, fmt.Errorf("invalid page %s: unsupported layout %s", pth, l)
+		}
+		p.layout = strings.TrimSuffix(l, ext)
+		p.renderer = renderer
+		return p, nil
+	}
+
+	return nil, fmt.Errorf("invalid page %s: layout must be a template file name or false", pth)
+}
+
+func (p *page) render(ctx context.Context) (string, error) {
+	_, span := tracer.Start(ctx, fmt.Sprintf("markdown.RenderPage %s", p.path),
+		trace.WithAttributes(
+			attribute.String("page", p.path),
+			attribute.String("layout", p.layout),
+		),
+	)
+
+	defer span.End()
+
+	data := map[string]any{"content": p.content}
+	for k, v := range p.frontMatter {
+		data[k] = v
+	}
+
+	res, err := p.renderer(ctx, p.path, p.layout, data)
+	if err != nil {
+		span.RecordError(err)
+		return "", fmt.Errorf("could not render page %s in layout %s: %w", p.path, p.layout, err)
+	}
+
+	return res, nil
+}
+
+// Register serves the pages as HTML. Layouts render the templates
+// selected by the front matter, keyed by the extension of the template.
+func (b *Builder) Register(log logr.Logger, r chi.Router, layouts map[string]Layout) error {
+	for pth := range b.files {
+		p, err := b.page(pth, layouts)
+		if err != nil {
+			return err
+		}
+
+		var handlerFunc http.HandlerFunc
+
+		if p.renderer == nil {
+			t := time.Now()
+			data := []byte(p.content)
+
+			handlerFunc = func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("content-type", "text/html; charset=utf-8")
+				http.ServeContent(w, r, r.URL.Path, t, bytes.NewReader(data))
+			}
+		} else {
+			handlerFunc = func(w http.ResponseWriter, r *http.Request) {
+				res, err := p.render(r.Context())
+				if err != nil {
+					log.Error(err, "could not render markdown page", "page", p.path)
+					http.Error(w, "internal server error", http.StatusInternalServerError)
+					return
+				}
+				w.Header().Set("content-type", "text/html; charset=utf-8")
+				w.Write([]byte(res))
+			}
+		}
+
+		r.Get(requestPath(pth), handlerFunc)
+	}
+
+	return nil
+}
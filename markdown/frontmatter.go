@@ -0,0 +1,43 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+var frontMatterDelimiter = []byte("---")
+
+// splitFrontMatter returns the YAML front matter of a page delimited by `---` lines
+// and the Markdown following it.
+func splitFrontMatter(src []byte) (map[string]any, []byte, error) {
+	fm := map[string]any{}
+
+	src = bytes.TrimPrefix(src, []byte("\uFEFF"))
+	first, rest, found := bytes.Cut(src, []byte("\n"))
+	if !found || !bytes.Equal(bytes.TrimRight(first, "\r"), frontMatterDelimiter) {
+		return fm, src, nil
+	}
+
+	for offset := 0; offset < len(rest); {
+		line, _, _ := bytes.Cut(rest[offset:], []byte("\n"))
+		end := offset + len(line) + 1
+		if bytes.Equal(bytes.TrimRight(line, "\r"), frontMatterDelimiter) {
+			err := yaml.Unmarshal(rest[:offset], &fm)
+			if err != nil {
+				return nil, nil, fmt.Errorf("could not parse front matter: %w", err)
+			}
+			if fm == nil {
+				fm = map[string]any{}
+			}
+			if end > len(rest) {
+				end = len(rest)
+			}
+			return fm, rest[end:], nil
+		}
+		offset = end
+	}
+
+	return nil, nil, fmt.Errorf("front matter is not terminated by ---")
+}
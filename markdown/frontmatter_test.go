@@ -0,0 +1,35 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitFrontMatter(t *testing.T) {
+	t.Run("pages without front matter", func(t *testing.T) {
+		fm, src, err := splitFrontMatter([]byte("# Title\n---\n"))
+		require.NoError(t, err)
+		require.Equal(t, map[string]any{}, fm)
+		require.Equal(t, "# Title\n---\n", string(src))
+	})
+
+	t.Run("front matter is parsed as YAML", func(t *testing.T) {
+		fm, src, err := splitFrontMatter([]byte("---\r\ntitle: Intro\r\ntags: [a, b]\r\n---\r\n# Intro\r\n"))
+		require.NoError(t, err)
+		require.Equal(t, map[string]any{"title": "Intro", "tags": []any{"a", "b"}}, fm)
+		require.Equal(t, "# Intro\r\n", string(src))
+	})
+
+	t.Run("empty front matter", func(t *testing.T) {
+		fm, src, err := splitFrontMatter([]byte("---\n---\n"))
+		require.NoError(t, err)
+		require.Equal(t, map[string]any{}, fm)
+		require.Equal(t, "", string(src))
+	})
+
+	t.Run("unterminated front matter", func(t *testing.T) {
+		_, _, err := splitFrontMatter([]byte("---\ntitle: Intro\n"))
+		require.EqualError(t, err, "front matter is not terminated by ---")
+	})
+}
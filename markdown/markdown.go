@@ -0,0 +1,47 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/draganm/go-lean/common/globals"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+	"go.opentelemetry.io/otel"
+)
+
+var tracer = otel.Tracer("github.com/draganm/go-lean/leanweb/markdown")
+
+// pages are part of the application, so they may contain raw HTML
+var pageRenderer = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+	goldmark.WithRendererOptions(html.WithUnsafe()),
+)
+
+// dynamic content is rendered without raw HTML, which is replaced by a comment
+var contentRenderer = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+)
+
+func convert(md goldmark.Markdown, src []byte) (string, error) {
+	buf := &bytes.Buffer{}
+	err := md.Convert(src, buf)
+	if err != nil {
+		return "", fmt.Errorf("could not render markdown: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// Provider provides the `markdown` global, `markdown.render(src)` returns the HTML of the Markdown source.
+// Raw HTML in the source is not rendered, so that it can be used for user content.
+func Provider() globals.Values {
+	return globals.Values{
+		"render": func(src string) (string, error) {
+			return convert(contentRenderer, []byte(src))
+		},
+	}
+}
@@ -0,0 +1,51 @@
+package lean_test
+
+import (
+	"context"
+	"io/fs"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/draganm/go-lean"
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarkdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sfs, err := fs.Sub(simple, "fixtures/markdown")
+	require.NoError(t, err)
+
+	w, err := lean.Construct(ctx, sfs, testr.New(t), map[string]any{})
+	require.NoError(t, err)
+
+	body := func(t *testing.T, target string) string {
+		rec := httptest.NewRecorder()
+		w.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		require.Equal(t, 200, rec.Code, rec.Body.String())
+		require.Equal(t, "text/html; charset=utf-8", rec.Header().Get("content-type"))
+		return rec.Body.String()
+	}
+
+	t.Run("pages without a layout are served as HTML", func(t *testing.T) {
+		require.Equal(t, "<h1 id=\"home\">Home</h1>\n<p>Welcome to <em>lean</em>.</p>\n", body(t, "/"))
+	})
+
+	t.Run("front matter selects a mustache layout", func(t *testing.T) {
+		require.Equal(t, "<title>Intro</title><main><h1 id=\"getting-started\">Getting started</h1>\n<p><em>raw</em></p>\n</main>", body(t, "/docs/intro"))
+	})
+
+	t.Run("front matter selects a pongo2 layout", func(t *testing.T) {
+		require.Equal(t, "<title>Filters</title><main><table>\n<thead>\n<tr>\n<th>a</th>\n<th>b</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td>1</td>\n<td>2</td>\n</tr>\n</tbody>\n</table>\n</main>", body(t, "/docs/filters"))
+	})
+
+	t.Run("front matter selects a Go template layout", func(t *testing.T) {
+		require.Equal(t, "<title>Templates</title><main><p>Use <code>gotemplate</code>.</p>\n</main>", body(t, "/docs/templates"))
+	})
+
+	t.Run("markdown global does not render raw HTML", func(t *testing.T) {
+		require.Equal(t, "<p><strong><!-- raw HTML omitted -->x<!-- raw HTML omitted --></strong></p>\n", body(t, "/render?text=%3Cb%3Ex%3C%2Fb%3E"))
+	})
+}
@@ -4,15 +4,31 @@ import (
 	"context"
 	"fmt"
 	"path"
+	"reflect"
 	"strings"
 
 	"github.com/dop251/goja"
+	"github.com/draganm/go-lean/common/compiler"
+	"github.com/draganm/go-lean/common/eventloop"
 	"github.com/draganm/go-lean/common/globals"
-	"github.com/draganm/go-lean/common/goja/fieldmapper"
+	"github.com/draganm/go-lean/common/jsruntime"
+	"github.com/draganm/go-lean/common/jsstack"
+	"github.com/draganm/go-lean/common/limits"
 	"github.com/go-logr/logr"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// Name is the name of the metric, e.g. `queue_length` for `/metrics/queue_length.gauge.js`.
+type Name string
+
+// InjectableTypes can be injected into providers of metric globals.
+var InjectableTypes = []reflect.Type{
+	globals.TypeOf[context.Context](),
+	globals.TypeOf[Name](),
+	globals.TypeOf[*goja.Runtime](),
+	globals.TypeOf[*eventloop.EventLoop](),
+}
+
 type Builder struct {
 	files map[string]func() ([]byte, error)
 }
@@ -43,14 +59,41 @@ func (b *Builder) Consume(pth string, getContent func() ([]byte, error)) bool {
 	return false
 }
 
-func (b *Builder) Start(ctx context.Context, log logr.Logger, gl globals.Globals) error {
+// Start registers collectors of the metrics, every metric gets a runtime created by the factory.
+// The time budget of the factory limits is enforced for every collection.
+// Globals of the metric runtimes are finished when the context is done.
+func (b *Builder) Start(ctx context.Context, log logr.Logger, factory *jsruntime.Factory) (err error) {
 
 	if len(b.files) == 0 {
 		return nil
 	}
 
+	env, err := factory.Environment("metrics", nil)
+	if err != nil {
+		return err
+	}
+
+	gl := env.Globals
+	lim := env.Limits
+
 	c := collector{}
 
+	finishers := []globals.Finish{}
+	finishAll := func(err error) {
+		for _, finish := range finishers {
+			ferr := finish(err)
+			if ferr != nil {
+				log.Error(ferr, "could not finish metric globals")
+			}
+		}
+	}
+
+	defer func() {
+		if err != nil {
+			finishAll(err)
+		}
+	}()
+
 	for pth, getContent := range b.files {
 
 		data, err := getContent()
@@ -63,21 +106,31 @@ func (b *Builder) Start(ctx context.Context, log logr.Logger, gl globals.Globals
 		handlerSubmatches := metricRegexp.FindStringSubmatch(fileName)
 
 		if len(handlerSubmatches) == 3 {
-			vm := goja.New()
-			vm.SetFieldNameMapper(fieldmapper.FallbackFieldMapper{})
+			vm, loop, err := env.New()
+			if err != nil {
+				return err
+			}
 
-			autoWired, err := gl.AutoWire(vm, context.Background())
+			autoWired, finish, err := gl.AutoWireFinish(InjectableTypes, vm, loop, Name(handlerSubmatches[1]), logr.NewContext(context.Background(), log.WithValues("metric", pth)))
 			if err != nil {
 				return fmt.Errorf("could not autowire globals: %w", err)
 			}
 
+			// globals of metrics live as long as the collectors are registered
+			finishers = append(finishers, finish)
+
 			for k, v := range autoWired {
 				err = vm.GlobalObject().Set(k, v)
 				if err != nil {
 					return fmt.Errorf("could not set global %s: %w", k, err)
 				}
 			}
-			_, err = vm.RunScript(pth, string(data))
+			prog, err := compiler.Compile(path.Join("/metrics", pth), string(data), false)
+			if err != nil {
+				return fmt.Errorf("could not compile metric handler %s: %w", pth, err)
+			}
+
+			err = compiler.RunScript(vm, prog)
 			if err != nil {
 				return fmt.Errorf("could not start metric handler %s: %w", pth, err)
 			}
@@ -88,6 +141,9 @@ func (b *Builder) Start(ctx context.Context, log logr.Logger, gl globals.Globals
 				return fmt.Errorf("could not export metric %s: %w", pth, err)
 			}
 
+			minfo.loop = loop
+			minfo.limits = lim
+			minfo.script = path.Join("/metrics", pth)
 			minfo.name = handlerSubmatches[1]
 			minfo.metricType = handlerSubmatches[2]
 			err = minfo.initialize()
@@ -99,7 +155,11 @@ func (b *Builder) Start(ctx context.Context, log logr.Logger, gl globals.Globals
 				log := log.WithValues("metric", pth)
 				met, err := minfo.collect()
 				if err != nil {
-					log.Error(err, "could not collect metric")
+					violation := limits.Report(minfo.script, err)
+					if violation != nil {
+						log = log.WithValues("limit", violation.Error())
+					}
+					log.Error(err, "could not collect metric", "jsStack", jsstack.FromError(err))
 					return nil
 				}
 				return met
@@ -114,6 +174,7 @@ func (b *Builder) Start(ctx context.Context, log logr.Logger, gl globals.Globals
 	go func() {
 		<-ctx.Done()
 		prometheus.Unregister(c)
+		finishAll(nil)
 	}()
 
 	return nil
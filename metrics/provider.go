@@ -1,15 +1,18 @@
 package metrics
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"sync"
 
 	"github.com/dop251/goja"
+	"github.com/draganm/go-lean/common/eventloop"
+	"github.com/draganm/go-lean/common/limits"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-var metricRegexp = regexp.MustCompile(`^([^/]+).(counter|gauge).js$`)
+var metricRegexp = regexp.MustCompile(`^([^/]+).(counter|gauge).(?:js|ts)$`)
 
 type metricProvider func() prometheus.Metric
 
@@ -21,8 +24,13 @@ func (c collector) Describe(dc chan<- *prometheus.Desc) {
 
 func (c collector) Collect(mc chan<- prometheus.Metric) {
 	for _, mp := range c {
-		if mp != nil {
-			mc <- mp()
+		if mp == nil {
+			continue
+		}
+		// failed collections are logged by the provider
+		m := mp()
+		if m != nil {
+			mc <- m
 		}
 	}
 }
@@ -33,10 +41,13 @@ type metricInfo struct {
 	Description    string            `lean:"description"`
 	Collect        goja.Callable     `lean:"collect"`
 
-	name string
-	vt   prometheus.ValueType
-	desc *prometheus.Desc
-	mu   *sync.Mutex
+	name   string
+	script string
+	loop   *eventloop.EventLoop
+	limits limits.Limits
+	vt     prometheus.ValueType
+	desc   *prometheus.Desc
+	mu     *sync.Mutex
 }
 
 func (m *metricInfo) initialize() error {
@@ -70,7 +81,11 @@ func (m *metricInfo) initialize() error {
 func (m *metricInfo) collect() (prometheus.Metric, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	v, err := m.Collect(nil)
+	ctx, budget := m.limits.StartBudget(context.Background(), m.loop.Runtime())
+	v, err := m.loop.Run(ctx, func(*goja.Runtime) (goja.Value, error) {
+		return m.Collect(nil)
+	})
+	err = budget.Stop(err)
 	if err != nil {
 		return nil, fmt.Errorf("could not collect %s: %w", m.name, err)
 	}
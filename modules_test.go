@@ -0,0 +1,48 @@
+package lean_test
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+
+	"github.com/draganm/go-lean"
+	"github.com/go-logr/logr/testr"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestESModulesAndTypeScript(t *testing.T) {
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sfs, err := fs.Sub(simple, "fixtures/modules")
+	require.NoError(err)
+
+	w, err := lean.Construct(ctx, sfs, testr.New(t), map[string]any{})
+	require.NoError(err)
+
+	require.HTTPStatusCode(w.ServeHTTP, "GET", "/greet/lean", nil, 200)
+	require.HTTPBodyContains(w.ServeHTTP, "GET", "/greet/lean", nil, "hello lean")
+
+	metrics := findMetrics(t, "typed_value", dto.MetricType_GAUGE)
+	require.Len(metrics, 1)
+	require.Equal(3.0, metrics[0].GetGauge().GetValue())
+}
+
+func TestRequire(t *testing.T) {
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sfs, err := fs.Sub(simple, "fixtures/modules")
+	require.NoError(err)
+
+	w, err := lean.Construct(ctx, sfs, testr.New(t), map[string]any{})
+	require.NoError(err)
+
+	require.HTTPBodyContains(w.ServeHTTP, "GET", "/shapes", nil, "circle,square true /lib/counter.js")
+	require.HTTPBodyContains(w.ServeHTTP, "GET", "/cycle", nil, "circular dependency: /lib/cycle/a.js -> /lib/cycle/b.js -> /lib/cycle/a.js")
+}
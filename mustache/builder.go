@@ -2,45 +2,63 @@ package mustache
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"path"
-	"strings"
 	"sync"
 
+	"github.com/dop251/goja"
 	"github.com/draganm/go-lean/common/globals"
 	"github.com/draganm/go-lean/web/types"
 )
 
 type Builder struct {
+	names types.TemplateFiles
+	errs  []error
 	files map[string]func() ([]byte, error)
 }
 
 func NewBuilder() *Builder {
 	return &Builder{
+		names: types.TemplateFiles{},
 		files: map[string]func() ([]byte, error){},
 	}
 }
 
 func (b *Builder) Consume(pth string, getContent func() ([]byte, error)) bool {
-	if !strings.HasPrefix(pth, "/web") {
+	name, isTemplate := types.TemplateName(pth)
+	if !isTemplate {
 		return false
 	}
 
-	pth = strings.TrimPrefix(pth, "/web")
-
-	_, fileName := path.Split(pth)
+	_, fileName := path.Split(name)
 
 	templateSubmatches := templateRegexp.FindStringSubmatch(fileName)
 	if len(templateSubmatches) == 2 {
-		b.files[pth] = getContent
+		err := b.names.Add(name, pth)
+		if err != nil {
+			b.errs = append(b.errs, err)
+		}
+		b.files[name] = getContent
 		return true
 	}
 
 	return false
 }
 
-func (b *Builder) Create() (MustacheProvider, error) {
+// Options configure the templates of the provider.
+type Options struct {
+	// Helpers returns values available in templates rendered with the context, e.g. lambdas.
+	// Data passed to render takes precedence.
+	Helpers func(ctx context.Context) map[string]any
+}
+
+func (b *Builder) Create(opts Options) (MustacheProvider, error) {
+	if len(b.errs) != 0 {
+		return nil, errors.Join(b.errs...)
+	}
+
 	templates := map[string]string{}
 
 	for pth, getContent := range b.files {
@@ -65,12 +83,19 @@ func (b *Builder) Create() (MustacheProvider, error) {
 		mu:            &sync.Mutex{},
 	}
 
-	return func(ctx context.Context, handlerPath types.HandlerPath, w http.ResponseWriter) globals.Values {
+	return func(ctx context.Context, handlerPath types.HandlerPath, w http.ResponseWriter, rt *goja.Runtime) globals.Values {
 
 		tc := tcf.getTemplateCacheForPath(path.Dir(string(handlerPath)))
+
+		var helpers map[string]any
+		if opts.Helpers != nil {
+			helpers = opts.Helpers(ctx)
+		}
+
 		return map[string]any{
-			"render":         renderTemplateForScope(ctx, tc, w),
-			"renderToString": renderTemplateForScopeToString(ctx, tc),
+			"render":         renderTemplateForScope(ctx, tc, helpers, w),
+			"renderToString": renderTemplateForScopeToString(ctx, tc, helpers),
+			"stream":         streamTemplateForScope(ctx, tc, helpers, w, rt),
 		}
 	}, nil
 
@@ -0,0 +1,74 @@
+package mustache
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/cbroglie/mustache"
+)
+
+// sectionSource returns the source of the first `{{#name}}` section of the template,
+// including its opening and closing tags. Templates changing the delimiters are not supported.
+func sectionSource(src, name string) (string, error) {
+	tagRegexp := regexp.MustCompile(`\{\{\s*([#^/])\s*` + regexp.QuoteMeta(name) + `\s*\}\}`)
+
+	start := -1
+	depth := 0
+	for _, loc := range tagRegexp.FindAllStringSubmatchIndex(src, -1) {
+		kind := src[loc[2]:loc[3]]
+
+		switch {
+		case start == -1 && kind == "#":
+			start = loc[0]
+			depth = 1
+		case start == -1:
+			continue
+		case kind == "/":
+			depth--
+			if depth == 0 {
+				return src[start:loc[1]], nil
+			}
+		default:
+			depth++
+		}
+	}
+
+	if start == -1 {
+		return "", fmt.Errorf("there is no section %s", name)
+	}
+
+	return "", fmt.Errorf("section %s is not closed", name)
+}
+
+// getFragment returns the template rendering only the section of the template.
+func (tc *scopedTemplateCache) getFragment(name, fragment string) (*mustache.Template, error) {
+	key := name + "#" + fragment
+
+	tc.mu.RLock()
+	template, found := tc.cached[key]
+	tc.mu.RUnlock()
+	if found {
+		return template, nil
+	}
+
+	templateString, err := tc.sp.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := sectionSource(templateString, fragment)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err = mustache.ParseStringPartials(src, tc.sp)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse fragment: %w", err)
+	}
+
+	tc.mu.Lock()
+	tc.cached[key] = template
+	tc.mu.Unlock()
+
+	return template, nil
+}
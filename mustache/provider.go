@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"regexp"
 
+	"github.com/dop251/goja"
 	"github.com/draganm/go-lean/common/globals"
 	"github.com/draganm/go-lean/web/types"
 	"go.opentelemetry.io/otel"
@@ -14,4 +15,23 @@ var tracer = otel.Tracer("github.com/draganm/go-lean/leanweb/mustache")
 
 var templateRegexp = regexp.MustCompile(`^(.+).mustache$`)
 
-type MustacheProvider func(ctx context.Context, handlerPath types.HandlerPath, w http.ResponseWriter) globals.Values
+type MustacheProvider func(ctx context.Context, handlerPath types.HandlerPath, w http.ResponseWriter, rt *goja.Runtime) globals.Values
+
+// RenderToString renders the template without a layout, resolving its name relative to handlerPath.
+func (p MustacheProvider) RenderToString(ctx context.Context, handlerPath types.HandlerPath, name string, data any) (string, error) {
+	render := p(ctx, handlerPath, nil, nil)["renderToString"].(func(string, any, *RenderOptions) (string, error))
+	return render(name, data, &RenderOptions{Layout: false})
+}
+
+// StandaloneProvider provides `mustache` to cron jobs and metrics, which have no response to render into.
+// `renderToString` resolves names relative to /templates and still uses their `_layout`.
+type StandaloneProvider func(ctx context.Context) globals.Values
+
+// Standalone returns the `mustache` global of cron jobs and metrics.
+func (p MustacheProvider) Standalone() StandaloneProvider {
+	return func(ctx context.Context) globals.Values {
+		return globals.Values{
+			"renderToString": p(ctx, types.StandaloneHandlerPath, nil, nil)["renderToString"],
+		}
+	}
+}
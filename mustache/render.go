@@ -9,13 +9,33 @@ import (
 	"sync"
 
 	"github.com/cbroglie/mustache"
+	"github.com/draganm/go-lean/web/types"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 )
 
-func renderTemplateForScope(ctx context.Context, tc *scopedTemplateCache, w io.Writer) func(name string, data interface{}) error {
+// RenderOptions of mustache templates, the third argument of `render`, `renderToString` and `stream`.
+type RenderOptions struct {
+	// Layout is the name of the template the rendered template is inserted into as `{{{content}}}`,
+	// or false for none. Defaults to the nearest `_layout.mustache`.
+	Layout any `lean:"layout"`
 
-	return func(name string, data any) error {
+	// Fragment is the name of a section, e.g. `rowList` for `{{#rowList}}...{{/rowList}}`.
+	// Only the section is rendered, without a layout, e.g. for htmx requests.
+	Fragment string `lean:"fragment"`
+}
+
+// contexts returns the data followed by the helpers, which are only used for names missing in the data.
+func contexts(data any, helpers map[string]any) []any {
+	if len(helpers) == 0 {
+		return []any{data}
+	}
+	return []any{data, helpers}
+}
+
+func renderTemplateForScope(ctx context.Context, tc *scopedTemplateCache, helpers map[string]any, w io.Writer) func(name string, data any, opts *RenderOptions) error {
+
+	return func(name string, data any, opts *RenderOptions) error {
 		_, span := tracer.Start(ctx, fmt.Sprintf("mustache.RenderTemplate %s", name),
 			trace.WithAttributes(
 				attribute.String("template", name),
@@ -24,20 +44,24 @@ func renderTemplateForScope(ctx context.Context, tc *scopedTemplateCache, w io.W
 
 		defer span.End()
 
-		template, err := tc.getTemplate(name)
+		template, layout, err := tc.getTemplateAndLayout(name, opts)
 		if err != nil {
 			span.RecordError(err)
-			return fmt.Errorf("could not get/parse template %s in scope %s: %w", name, tc.sp.scope, err)
+			return err
+		}
+
+		if layout != nil {
+			return template.FRenderInLayout(w, layout, contexts(data, helpers)...)
 		}
 
-		return template.FRender(w, data)
+		return template.FRender(w, contexts(data, helpers)...)
 	}
 
 }
 
-func renderTemplateForScopeToString(ctx context.Context, tc *scopedTemplateCache) func(name string, data interface{}) (string, error) {
+func renderTemplateForScopeToString(ctx context.Context, tc *scopedTemplateCache, helpers map[string]any) func(name string, data any, opts *RenderOptions) (string, error) {
 
-	return func(name string, data any) (string, error) {
+	return func(name string, data any, opts *RenderOptions) (string, error) {
 		_, span := tracer.Start(ctx, fmt.Sprintf("mustache.RenderTemplateToString %s", name),
 			trace.WithAttributes(
 				attribute.String("template", name),
@@ -45,13 +69,17 @@ func renderTemplateForScopeToString(ctx context.Context, tc *scopedTemplateCache
 		)
 
 		defer span.End()
-		template, err := tc.getTemplate(name)
+		template, layout, err := tc.getTemplateAndLayout(name, opts)
 		if err != nil {
 			span.RecordError(err)
-			return "", fmt.Errorf("could not get/parse template %s in scope %s: %w", name, tc.sp.scope, err)
+			return "", err
+		}
+
+		if layout != nil {
+			return template.RenderInLayout(layout, contexts(data, helpers)...)
 		}
 
-		return template.Render(data)
+		return template.Render(contexts(data, helpers)...)
 	}
 
 }
@@ -109,16 +137,66 @@ func (tc *scopedTemplateCache) getTemplate(name string) (*mustache.Template, err
 	return template, nil
 }
 
+// getTemplateAndLayout returns the template and the layout it is rendered in,
+// the layout is nil if the template is rendered on its own.
+func (tc *scopedTemplateCache) getTemplateAndLayout(name string, opts *RenderOptions) (*mustache.Template, *mustache.Template, error) {
+	if opts != nil && opts.Fragment != "" {
+		template, err := tc.getFragment(name, opts.Fragment)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not get/parse fragment %s of template %s in scope %s: %w", opts.Fragment, name, tc.sp.scope, err)
+		}
+		return template, nil, nil
+	}
+
+	template, err := tc.getTemplate(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not get/parse template %s in scope %s: %w", name, tc.sp.scope, err)
+	}
+
+	layoutName, err := tc.layoutName(name, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if layoutName == "" {
+		return template, nil, nil
+	}
+
+	lt, err := tc.getTemplate(layoutName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not get/parse layout %s of template %s in scope %s: %w", layoutName, name, tc.sp.scope, err)
+	}
+
+	return template, lt, nil
+}
+
+// layoutName returns the name of the layout the template is rendered in, empty if there is none.
+func (tc *scopedTemplateCache) layoutName(name string, opts *RenderOptions) (string, error) {
+	var option any
+	if opts != nil {
+		option = opts.Layout
+	}
+
+	return types.Layout(tc.sp.resolve(name), option, tc.sp.resolve, func(layout string) bool {
+		_, found := tc.sp.partials[layout]
+		return found
+	})
+}
+
 type scopedPartialProvider struct {
 	partials map[string]string
 	scope    string
 }
 
-func (sp scopedPartialProvider) Get(name string) (string, error) {
+func (sp scopedPartialProvider) resolve(name string) string {
 	if !strings.HasPrefix(name, "/") {
 		name = path.Join(sp.scope, name)
 	}
-	name = path.Clean(name)
+	return path.Clean(name)
+}
+
+func (sp scopedPartialProvider) Get(name string) (string, error) {
+	name = sp.resolve(name)
 	partial, found := sp.partials[name]
 	if !found {
 		return "", fmt.Errorf("could not find mustache partial %s", name)
@@ -0,0 +1,271 @@
+package mustache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/cbroglie/mustache"
+	"github.com/dop251/goja"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	contentRegexp = regexp.MustCompile(`\{\{\s*(\{\s*content\s*\}|&\s*content)\s*\}\}`)
+	sectionRegexp = regexp.MustCompile(`\{\{\s*([#^/])\s*([^\s}]+)\s*\}\}`)
+)
+
+// splitSections splits the template into the text between top level sections and the sections,
+// including their opening and closing tags. Templates changing the delimiters are not supported.
+func splitSections(src string) []string {
+	segments := []string{}
+	start := 0
+	depth := 0
+
+	for _, loc := range sectionRegexp.FindAllStringSubmatchIndex(src, -1) {
+		kind := src[loc[2]:loc[3]]
+
+		if kind != "/" {
+			if depth == 0 {
+				segments = append(segments, src[start:loc[0]])
+				start = loc[0]
+			}
+			depth++
+			continue
+		}
+
+		depth--
+		if depth == 0 {
+			segments = append(segments, src[start:loc[1]])
+			start = loc[1]
+		}
+	}
+
+	segments = append(segments, src[start:])
+
+	res := []string{}
+	for _, s := range segments {
+		if s != "" {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// usedNames returns the top level names used by the tags, and whether
+// partials are used, which can use any name.
+func usedNames(tags []mustache.Tag, names map[string]bool) bool {
+	usesPartials := false
+	for _, t := range tags {
+		switch t.Type() {
+		case mustache.Partial:
+			usesPartials = true
+			continue
+		case mustache.Section, mustache.InvertedSection:
+			if usedNames(t.Tags(), names) {
+				usesPartials = true
+			}
+		}
+		name, _, _ := strings.Cut(t.Name(), ".")
+		names[name] = true
+	}
+	return usesPartials
+}
+
+type streamSegment struct {
+	template *mustache.Template
+	names    map[string]bool
+	all      bool
+}
+
+// streamState renders segments of the page as the promises they use are settled.
+type streamState struct {
+	rt       *goja.Runtime
+	w        http.ResponseWriter
+	data     map[string]any
+	pending  map[string]*goja.Promise
+	helpers  map[string]any
+	segments []streamSegment
+	resolve  func(any)
+	reject   func(any)
+}
+
+func (s *streamState) next(i int) {
+	if i == len(s.segments) {
+		s.resolve(goja.Undefined())
+		return
+	}
+
+	seg := s.segments[i]
+	for name, p := range s.pending {
+		if !seg.all && !seg.names[name] {
+			continue
+		}
+
+		switch p.State() {
+		case goja.PromiseStatePending:
+			s.wait(name, p, func() { s.next(i) })
+			return
+		case goja.PromiseStateRejected:
+			s.reject(p.Result())
+			return
+		default:
+			s.data[name] = p.Result().Export()
+			delete(s.pending, name)
+		}
+	}
+
+	err := seg.template.FRender(s.w, contexts(s.data, s.helpers)...)
+	if err != nil {
+		s.reject(s.rt.NewGoError(fmt.Errorf("could not render template: %w", err)))
+		return
+	}
+
+	err = http.NewResponseController(s.w).Flush()
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		s.reject(s.rt.NewGoError(fmt.Errorf("could not flush response: %w", err)))
+		return
+	}
+
+	s.next(i + 1)
+}
+
+// wait continues once the promise is settled, the result is checked by next.
+func (s *streamState) wait(name string, p *goja.Promise, cont func()) {
+	obj := s.rt.ToValue(p).ToObject(s.rt)
+	then, isFunction := goja.AssertFunction(obj.Get("then"))
+	if !isFunction {
+		s.reject(s.rt.NewTypeError("%s is not a promise", name))
+		return
+	}
+
+	settled := s.rt.ToValue(func(goja.Value) { cont() })
+	_, err := then(obj, settled, settled)
+	if err != nil {
+		s.reject(err)
+	}
+}
+
+func (tc *scopedTemplateCache) parseSegments(src string) ([]streamSegment, error) {
+	segments := []streamSegment{}
+	for _, part := range splitSections(src) {
+		t, err := mustache.ParseStringPartials(part, tc.sp)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse template: %w", err)
+		}
+		names := map[string]bool{}
+		all := usedNames(t.Tags(), names)
+		segments = append(segments, streamSegment{template: t, names: names, all: all})
+	}
+	return segments, nil
+}
+
+// streamSegments returns the segments of the template in its layout: the layout up to `{{{content}}}`,
+// the sections of the template and the text between them, and the rest of the layout.
+func (tc *scopedTemplateCache) streamSegments(name string, opts *RenderOptions) ([]streamSegment, error) {
+	if opts != nil && opts.Fragment != "" {
+		return nil, fmt.Errorf("fragments can't be streamed")
+	}
+
+	src, err := tc.sp.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	segments, err := tc.parseSegments(src)
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", name, err)
+	}
+
+	layoutName, err := tc.layoutName(name, opts)
+	if err != nil || layoutName == "" {
+		return segments, err
+	}
+
+	layoutSrc, err := tc.sp.Get(layoutName)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := contentRegexp.FindStringIndex(layoutSrc)
+	if loc == nil {
+		return nil, fmt.Errorf("layout %s does not render {{{content}}}", layoutName)
+	}
+
+	head, err := tc.parseSegments(layoutSrc[:loc[0]])
+	if err != nil {
+		return nil, fmt.Errorf("layout %s: %w", layoutName, err)
+	}
+
+	tail, err := tc.parseSegments(layoutSrc[loc[1]:])
+	if err != nil {
+		return nil, fmt.Errorf("layout %s: %w", layoutName, err)
+	}
+
+	res := append(head, segments...)
+	return append(res, tail...), nil
+}
+
+// streamTemplateForScope returns `stream(name, data, options)`. Values of data can be promises,
+// each part of the page is rendered and flushed as soon as the values it uses are resolved.
+func streamTemplateForScope(ctx context.Context, tc *scopedTemplateCache, helpers map[string]any, w http.ResponseWriter, rt *goja.Runtime) func(name string, data *goja.Object, opts *RenderOptions) (*goja.Promise, error) {
+	return func(name string, data *goja.Object, opts *RenderOptions) (*goja.Promise, error) {
+		_, span := tracer.Start(ctx, fmt.Sprintf("mustache.StreamTemplate %s", name),
+			trace.WithAttributes(
+				attribute.String("template", name),
+			),
+		)
+
+		segments, err := tc.streamSegments(name, opts)
+		if err != nil {
+			err = fmt.Errorf("could not stream template %s in scope %s: %w", name, tc.sp.scope, err)
+			span.RecordError(err)
+			span.End()
+			return nil, err
+		}
+
+		promise, resolvePromise, rejectPromise := rt.NewPromise()
+
+		resolve := func(v any) {
+			span.End()
+			resolvePromise(v)
+		}
+
+		reject := func(reason any) {
+			span.RecordError(fmt.Errorf("could not stream template %s: %v", name, reason))
+			span.End()
+			rejectPromise(reason)
+		}
+
+		s := &streamState{
+			rt:       rt,
+			w:        w,
+			data:     map[string]any{},
+			pending:  map[string]*goja.Promise{},
+			helpers:  helpers,
+			segments: segments,
+			resolve:  resolve,
+			reject:   reject,
+		}
+
+		if data != nil {
+			for _, k := range data.Keys() {
+				v := data.Get(k)
+				p, isPromise := v.Export().(*goja.Promise)
+				if isPromise {
+					s.pending[k] = p
+					continue
+				}
+				s.data[k] = v.Export()
+			}
+		}
+
+		s.next(0)
+
+		return promise, nil
+	}
+}
@@ -0,0 +1,23 @@
+package mustache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitSections(t *testing.T) {
+	require.Equal(t,
+		[]string{"<h1>{{title}}</h1>", "{{#rows}}{{#rows}}x{{/rows}}{{/rows}}", "<p>", "{{^empty}}none{{/empty}}", "</p>"},
+		splitSections("<h1>{{title}}</h1>{{#rows}}{{#rows}}x{{/rows}}{{/rows}}<p>{{^empty}}none{{/empty}}</p>"),
+	)
+}
+
+func TestSectionSource(t *testing.T) {
+	src, err := sectionSource("a{{#rows}}{{# rows }}x{{/rows}}{{/ rows}}b", "rows")
+	require.NoError(t, err)
+	require.Equal(t, "{{#rows}}{{# rows }}x{{/rows}}{{/ rows}}", src)
+
+	_, err = sectionSource("{{#other}}{{/other}}", "rows")
+	require.EqualError(t, err, "there is no section rows")
+}
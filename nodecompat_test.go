@@ -0,0 +1,61 @@
+package lean_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/draganm/go-lean"
+	"github.com/draganm/go-lean/common/nodecompat"
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodeCompat(t *testing.T) {
+	require := require.New(t)
+
+	t.Setenv("LEAN_TEST_ALLOWED", "visible")
+	t.Setenv("LEAN_TEST_DENIED", "hidden")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sfs, err := fs.Sub(simple, "fixtures/nodecompat")
+	require.NoError(err)
+
+	mu := &sync.Mutex{}
+	logged := []string{}
+	log := funcr.New(func(prefix, args string) {
+		mu.Lock()
+		defer mu.Unlock()
+		logged = append(logged, prefix+" "+args)
+	}, funcr.Options{})
+
+	w, err := lean.Construct(ctx, sfs, log, map[string]any{}, lean.WithNodeCompat(nodecompat.Options{
+		EnvAllowlist: []string{"LEAN_TEST_ALLOWED", "LEAN_TEST_DENIED_NOT"},
+	}))
+	require.NoError(err)
+
+	rec := httptest.NewRecorder()
+	w.ServeHTTP(rec, httptest.NewRequest("GET", "/compat", nil))
+	require.Equal(200, rec.Code)
+
+	res := map[string]any{}
+	require.NoError(json.Unmarshal(rec.Body.Bytes(), &res))
+	require.Equal(map[string]any{
+		"host":          "example.com",
+		"shape":         "circle",
+		"base64":        "aGVsbG8=",
+		"encodedLength": 6.0,
+		"decoded":       "héllo",
+		"allowed":       "visible",
+	}, res)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Contains(strings.Join(logged, "\n"), `"msg"="handling compat" "method"="GET" "handlerPath"="/compat"`)
+}
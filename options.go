@@ -0,0 +1,172 @@
+package lean
+
+import (
+	"html/template"
+
+	"github.com/draganm/go-lean/common/globals"
+	"github.com/draganm/go-lean/common/jslog"
+	"github.com/draganm/go-lean/common/limits"
+	"github.com/draganm/go-lean/common/nodecompat"
+	"github.com/draganm/go-lean/i18n"
+	"github.com/draganm/go-lean/mail"
+	"github.com/draganm/go-lean/shared"
+	"github.com/draganm/go-lean/web/jshandler"
+	"github.com/flosch/pongo2/v6"
+)
+
+type options struct {
+	handlerOptions jshandler.Options
+	nodeCompat     *nodecompat.Options
+	camelCase      bool
+	logLevel       jslog.Level
+	sharedStore    *shared.Store
+	modules        []globals.Module
+	limits         limits.Limits
+	pongo2Filters  map[string]pongo2.FilterFunction
+	pongo2Tags     map[string]pongo2.TagParser
+	templateFuncs  template.FuncMap
+	i18n           i18n.Options
+	mail           *mail.Options
+}
+
+// Option customizes the lean handler created by Construct.
+type Option func(*options)
+
+// WithAuthenticator sets the authenticator used for handlers
+// that have `requireAuth` set in their config.
+func WithAuthenticator(a jshandler.Authenticator) Option {
+	return func(o *options) {
+		o.handlerOptions.Authenticator = a
+	}
+}
+
+// WithDevMode makes handlers respond with an HTML page showing
+// the JavaScript stack and source excerpt when they fail.
+// Should not be used in production, since it exposes the source code.
+func WithDevMode() Option {
+	return func(o *options) {
+		o.handlerOptions.DevMode = true
+	}
+}
+
+// WithNodeCompat installs `console`, `Buffer`, `URL`, `URLSearchParams`,
+// `TextEncoder`, `TextDecoder` and `process.env` into every runtime.
+// `console` logs to the logger of the request, cron job or metric.
+func WithNodeCompat(opts nodecompat.Options) Option {
+	return func(o *options) {
+		o.nodeCompat = &opts
+	}
+}
+
+// WithCamelCase exposes methods and untagged fields of Go values with lowerCamel
+// names to JavaScript, e.g. `w.write()` and `r.header.get()` instead of
+// `w.Write()` and `r.Header.Get()`. Names from `lean` tags are used as they are.
+func WithCamelCase() Option {
+	return func(o *options) {
+		o.camelCase = true
+	}
+}
+
+// WithLogLevel sets the minimum level of messages logged through the `log` global.
+// Debug messages are logged with verbosity 1, so the logger has to enable it as well.
+// Defaults to jslog.LevelDebug.
+func WithLogLevel(l jslog.Level) Option {
+	return func(o *options) {
+		o.logLevel = l
+	}
+}
+
+// WithLimits sets resource limits of all runtimes: the maximum call stack size,
+// the time budget of every handler, cron job and metric invocation, and the maximum
+// size of handler responses. Violations are logged with the violated limit and
+// counted in the `lean_resource_limit_violations_count` metric.
+func WithLimits(l limits.Limits) Option {
+	return func(o *options) {
+		o.limits = l
+	}
+}
+
+// WithIsolation sets how the global state of pooled handler runtimes is treated
+// between requests, see jshandler.IsolationRestore and jshandler.IsolationFreeze.
+func WithIsolation(i jshandler.Isolation) Option {
+	return func(o *options) {
+		o.handlerOptions.Isolation = i
+	}
+}
+
+// WithGlobalMutationLog logs every request that has changed the global state
+// of a handler runtime. Useful for finding handlers relying on state leaking
+// between requests before enabling isolation.
+func WithGlobalMutationLog() Option {
+	return func(o *options) {
+		o.handlerOptions.LogGlobalMutations = true
+	}
+}
+
+// WithSharedStore sets the store backing the `shared` global, so that
+// the embedding application can read and write the same values.
+// A new store is created by default.
+func WithSharedStore(s *shared.Store) Option {
+	return func(o *options) {
+		o.sharedStore = s
+	}
+}
+
+// WithModules registers documented globals. They are available the same way as globals
+// passed to Construct, and their docs are part of the generated TypeScript declarations,
+// see TypeScriptDeclarations.
+func WithModules(mods ...globals.Module) Option {
+	return func(o *options) {
+		o.modules = append(o.modules, mods...)
+	}
+}
+
+// WithPongo2Filter registers a Go filter for pongo2 templates, used as `{{ value|name:param }}`.
+// Filters can also be written in JavaScript as `/templates/_filters/<name>.js`.
+// pongo2 filters are registered for the whole process, a name can't be registered with another function.
+func WithPongo2Filter(name string, fn pongo2.FilterFunction) Option {
+	return func(o *options) {
+		if o.pongo2Filters == nil {
+			o.pongo2Filters = map[string]pongo2.FilterFunction{}
+		}
+		o.pongo2Filters[name] = fn
+	}
+}
+
+// WithPongo2Tag registers a Go tag for pongo2 templates, used as `{% name %}`.
+// pongo2 tags are registered for the whole process, a name can't be registered with another parser function.
+func WithPongo2Tag(name string, parser pongo2.TagParser) Option {
+	return func(o *options) {
+		if o.pongo2Tags == nil {
+			o.pongo2Tags = map[string]pongo2.TagParser{}
+		}
+		o.pongo2Tags[name] = parser
+	}
+}
+
+// WithTemplateFuncs adds functions to `.gohtml` templates rendered by the `gotemplate` global.
+func WithTemplateFuncs(funcs template.FuncMap) Option {
+	return func(o *options) {
+		if o.templateFuncs == nil {
+			o.templateFuncs = template.FuncMap{}
+		}
+		for k, v := range funcs {
+			o.templateFuncs[k] = v
+		}
+	}
+}
+
+// WithI18n configures the default locale, locale negotiation and checks of the `/i18n` catalogs.
+func WithI18n(opts i18n.Options) Option {
+	return func(o *options) {
+		o.i18n = opts
+	}
+}
+
+// WithMail sets the `mail` global, delivering messages through the transport of the options.
+// Bodies are rendered from templates under /templates unless Templates are set.
+func WithMail(opts mail.Options) Option {
+	return func(o *options) {
+		o.mail = &opts
+	}
+}
@@ -3,40 +3,54 @@ package pongo2
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path"
-	"strings"
 	"sync"
 
+	"github.com/dop251/goja"
 	"github.com/draganm/go-lean/common/globals"
 	"github.com/draganm/go-lean/web/types"
 	"github.com/flosch/pongo2/v6"
 )
 
 type Builder struct {
-	files map[string]func() ([]byte, error)
+	names   types.TemplateFiles
+	errs    []error
+	files   map[string]func() ([]byte, error)
+	filters map[string]func() ([]byte, error)
 }
 
 func NewBuilder() *Builder {
 	return &Builder{
-		files: map[string]func() ([]byte, error){},
+		names:   types.TemplateFiles{},
+		files:   map[string]func() ([]byte, error){},
+		filters: map[string]func() ([]byte, error){},
 	}
 }
 
 func (b *Builder) Consume(pth string, getContent func() ([]byte, error)) bool {
-	if !strings.HasPrefix(pth, "/web") {
-		return false
+	if filterRegexp.MatchString(pth) {
+		b.filters[pth] = getContent
+		return true
 	}
 
-	pth = strings.TrimPrefix(pth, "/web")
+	name, isTemplate := types.TemplateName(pth)
+	if !isTemplate {
+		return false
+	}
 
-	_, fileName := path.Split(pth)
+	_, fileName := path.Split(name)
 
 	if templateRegexp.MatchString(fileName) {
-		b.files[pth] = getContent
+		err := b.names.Add(name, pth)
+		if err != nil {
+			b.errs = append(b.errs, err)
+		}
+		b.files[name] = getContent
 		return true
 	}
 
@@ -77,7 +91,16 @@ func (tl *templateLoader) Get(path string) (io.Reader, error) {
 	return bytes.NewReader(data), nil
 }
 
-func (b *Builder) Create() (Pongo2Provider, error) {
+// Create registers the filters and tags, and returns the provider of the `pongo2` global.
+func (b *Builder) Create(opts Options) (Pongo2Provider, error) {
+	if len(b.errs) != 0 {
+		return nil, errors.Join(b.errs...)
+	}
+
+	err := b.register(opts)
+	if err != nil {
+		return nil, err
+	}
 
 	loader := &templateLoader{
 		mu:    &sync.RWMutex{},
@@ -86,26 +109,19 @@ func (b *Builder) Create() (Pongo2Provider, error) {
 
 	ts := pongo2.NewSet("lean", loader)
 
-	return func(ctx context.Context, handlerPath types.HandlerPath, w http.ResponseWriter) globals.Values {
-
-		return map[string]any{
-			"render": func(name string, vals pongo2.Context) error {
+	return func(ctx context.Context, handlerPath types.HandlerPath, w http.ResponseWriter, rt *goja.Runtime) globals.Values {
 
-				if !strings.HasSuffix(name, ".pongo2") {
-					name = name + ".pongo2"
-				}
+		scope := path.Dir(string(handlerPath))
 
-				if !strings.HasPrefix(name, "/") {
-					name = path.Join(path.Dir(string(handlerPath)), name)
-				}
-
-				template, err := ts.FromCache(name)
-				if err != nil {
-					return fmt.Errorf("could not get template %s: %w", name, err)
-				}
+		var helpers map[string]any
+		if opts.Helpers != nil {
+			helpers = opts.Helpers(ctx)
+		}
 
-				return template.ExecuteWriter(vals, w)
-			},
+		return map[string]any{
+			"render":         renderTemplateForScope(ctx, ts, scope, helpers, w),
+			"renderToString": renderTemplateForScopeToString(ctx, ts, scope, helpers),
+			"stream":         streamTemplateForScope(ctx, ts, scope, helpers, w, rt),
 		}
 
 	}, nil
@@ -0,0 +1,232 @@
+package pongo2
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"path"
+	"reflect"
+	"regexp"
+	"sync"
+
+	"github.com/dop251/goja"
+	"github.com/draganm/go-lean/common/compiler"
+	"github.com/draganm/go-lean/common/limits"
+	"github.com/flosch/pongo2/v6"
+)
+
+var filterRegexp = regexp.MustCompile(`^/templates/_filters/([a-zA-Z_][a-zA-Z0-9_]*)\.(js|ts)$`)
+
+// Options configure the template set of the provider.
+type Options struct {
+	// Filters are Go filters, usable in templates as `{{ value|name:param }}`.
+	Filters map[string]pongo2.FilterFunction
+
+	// Tags are Go tags, usable in templates as `{% name %}`.
+	Tags map[string]pongo2.TagParser
+
+	// Helpers returns values added to the context of templates rendered with the context,
+	// unless the context passed to render sets them.
+	Helpers func(ctx context.Context) map[string]any
+
+	// NewRuntime creates runtimes for filters written in JavaScript.
+	NewRuntime func() (*goja.Runtime, error)
+
+	// Limits bound every call of a filter written in JavaScript.
+	Limits limits.Limits
+}
+
+// pongo2 registers filters and tags for the whole process and binds them when templates are parsed,
+// so a name can only be registered again with the same function, e.g. when the same application
+// is constructed again. Filters are identified by their Go function or by their script.
+var (
+	registryMu        = &sync.Mutex{}
+	registeredTags    = map[string]uintptr{}
+	registeredFilters = map[string]string{}
+)
+
+func registerTag(name string, parser pongo2.TagParser) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	fn := reflect.ValueOf(parser).Pointer()
+
+	registered, found := registeredTags[name]
+	if found {
+		if registered != fn {
+			return fmt.Errorf("tag %s is already registered with another parser", name)
+		}
+		return nil
+	}
+
+	err := pongo2.RegisterTag(name, parser)
+	if err != nil {
+		return err
+	}
+
+	registeredTags[name] = fn
+	return nil
+}
+
+// goFilterID identifies a Go filter by its function.
+func goFilterID(fn pongo2.FilterFunction) string {
+	return fmt.Sprintf("func %x", reflect.ValueOf(fn).Pointer())
+}
+
+// scriptFilterID identifies a JavaScript filter by its file and source.
+func scriptFilterID(fileName string, src []byte) string {
+	return fmt.Sprintf("script %s %x", fileName, sha256.Sum256(src))
+}
+
+// registerFilter registers the filter, or replaces the filter registered with the same id,
+// so that JavaScript filters use the runtimes of the latest application.
+func registerFilter(name, id string, fn pongo2.FilterFunction) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	registered, found := registeredFilters[name]
+	if found {
+		if registered != id {
+			return fmt.Errorf("filter %s is already registered with another function", name)
+		}
+		return pongo2.ReplaceFilter(name, fn)
+	}
+
+	if pongo2.FilterExists(name) {
+		return fmt.Errorf("filter %s is a built-in filter", name)
+	}
+
+	err := pongo2.RegisterFilter(name, fn)
+	if err != nil {
+		return err
+	}
+
+	registeredFilters[name] = id
+	return nil
+}
+
+// jsFilter calls the `filter(value, param)` function of a script in /templates/_filters.
+// Every concurrent call gets its own runtime and is interrupted when it exceeds the time budget.
+func jsFilter(name, fileName string, prog *goja.Program, newRuntime func() (*goja.Runtime, error), lim limits.Limits) (pongo2.FilterFunction, error) {
+
+	type instance struct {
+		rt *goja.Runtime
+		fn goja.Callable
+	}
+
+	createInstance := func() (*instance, error) {
+		rt, err := newRuntime()
+		if err != nil {
+			return nil, err
+		}
+
+		err = compiler.RunScript(rt, prog)
+		if err != nil {
+			return nil, fmt.Errorf("could not run filter script %s: %w", fileName, err)
+		}
+
+		fn, isFunction := goja.AssertFunction(rt.Get("filter"))
+		if !isFunction {
+			return nil, fmt.Errorf("filter script %s does not define a filter() function", fileName)
+		}
+
+		return &instance{rt: rt, fn: fn}, nil
+	}
+
+	canary, err := createInstance()
+	if err != nil {
+		return nil, err
+	}
+
+	pool := &sync.Pool{}
+	pool.Put(canary)
+
+	sender := fmt.Sprintf("filter:%s", name)
+
+	return func(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+		inst, _ := pool.Get().(*instance)
+		if inst == nil {
+			var err error
+			inst, err = createInstance()
+			if err != nil {
+				return nil, &pongo2.Error{Sender: sender, OrigError: err}
+			}
+		}
+		defer pool.Put(inst)
+
+		args := []goja.Value{inst.rt.ToValue(in.Interface())}
+		if param != nil && !param.IsNil() {
+			args = append(args, inst.rt.ToValue(param.Interface()))
+		}
+
+		_, budget := lim.StartBudget(context.Background(), inst.rt)
+		res, err := inst.fn(nil, args...)
+		err = budget.Stop(err)
+		if err != nil {
+			limits.Report(fileName, err)
+			return nil, &pongo2.Error{Sender: sender, OrigError: err}
+		}
+
+		return pongo2.AsValue(res.Export()), nil
+	}, nil
+}
+
+// register registers the Go and JavaScript filters and the tags.
+func (b *Builder) register(opts Options) error {
+	errs := []error{}
+
+	for name, fn := range opts.Filters {
+		err := registerFilter(name, goFilterID(fn), fn)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not register filter %s: %w", name, err))
+		}
+	}
+
+	for name, parser := range opts.Tags {
+		err := registerTag(name, parser)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not register tag %s: %w", name, err))
+		}
+	}
+
+	for pth, getContent := range b.filters {
+		name := filterRegexp.FindStringSubmatch(pth)[1]
+
+		_, defined := opts.Filters[name]
+		if defined {
+			errs = append(errs, fmt.Errorf("filter %s is defined both in Go and in %s", name, pth))
+			continue
+		}
+
+		if opts.NewRuntime == nil {
+			errs = append(errs, fmt.Errorf("could not create filter %s: no runtime factory", name))
+			continue
+		}
+
+		data, err := getContent()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not get content of %s: %w", pth, err))
+			continue
+		}
+
+		prog, err := compiler.Compile(path.Clean(pth), string(data), false)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not compile %s: %w", pth, err))
+			continue
+		}
+
+		fn, err := jsFilter(name, pth, prog, opts.NewRuntime, opts.Limits)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not create filter %s: %w", name, err))
+			continue
+		}
+
+		err = registerFilter(name, scriptFilterID(pth, data), fn)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not register filter %s: %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
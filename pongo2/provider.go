@@ -5,13 +5,34 @@ import (
 	"net/http"
 	"regexp"
 
+	"github.com/dop251/goja"
 	"github.com/draganm/go-lean/common/globals"
 	"github.com/draganm/go-lean/web/types"
+	"github.com/flosch/pongo2/v6"
 	"go.opentelemetry.io/otel"
 )
 
-var tracer = otel.Tracer("github.com/draganm/go-lean/leanweb/mustache")
+var tracer = otel.Tracer("github.com/draganm/go-lean/leanweb/pongo2")
 
 var templateRegexp = regexp.MustCompile(`^(.+).pongo2$`)
 
-type Pongo2Provider func(ctx context.Context, handlerPath types.HandlerPath, w http.ResponseWriter) globals.Values
+type Pongo2Provider func(ctx context.Context, handlerPath types.HandlerPath, w http.ResponseWriter, rt *goja.Runtime) globals.Values
+
+// RenderToString renders the template, resolving its name relative to handlerPath.
+func (p Pongo2Provider) RenderToString(ctx context.Context, handlerPath types.HandlerPath, name string, vals map[string]any) (string, error) {
+	render := p(ctx, handlerPath, nil, nil)["renderToString"].(func(string, pongo2.Context, *RenderOptions) (string, error))
+	return render(name, vals, nil)
+}
+
+// StandaloneProvider provides `pongo2` outside of handlers with `renderToString` only, e.g. to render emails
+// in cron jobs. Names passed to it are relative to /templates.
+type StandaloneProvider func(ctx context.Context) globals.Values
+
+// Standalone returns the `pongo2` global used outside of handlers.
+func (p Pongo2Provider) Standalone() StandaloneProvider {
+	return func(ctx context.Context) globals.Values {
+		return globals.Values{
+			"renderToString": p(ctx, types.StandaloneHandlerPath, nil, nil)["renderToString"],
+		}
+	}
+}
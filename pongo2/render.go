@@ -1,127 +1,180 @@
 package pongo2
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"path"
 	"strings"
-	"sync"
 
-	"github.com/cbroglie/mustache"
+	"github.com/flosch/pongo2/v6"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 )
 
-func renderTemplateForScope(ctx context.Context, tc *scopedTemplateCache, w io.Writer) func(name string, data interface{}) error {
-
-	return func(name string, data any) error {
-		_, span := tracer.Start(ctx, fmt.Sprintf("mustache.RenderTemplate %s", name),
-			trace.WithAttributes(
-				attribute.String("template", name),
-			),
-		)
-
-		defer span.End()
-
-		template, err := tc.getTemplate(name)
-		if err != nil {
-			span.RecordError(err)
-			return fmt.Errorf("could not get/parse template %s in scope %s: %w", name, tc.sp.scope, err)
-		}
+// TemplateError is an error located in a template, either while
+// parsing it or while executing it.
+type TemplateError struct {
+	Template string
+	Line     int
+	Column   int
+	Err      *pongo2.Error
+}
 
-		return template.FRender(w, data)
+func (e *TemplateError) Error() string {
+	msg := e.Err.OrigError.Error()
+	if e.Err.Sender != "" {
+		msg = fmt.Sprintf("%s: %s", e.Err.Sender, msg)
 	}
-
+	return fmt.Sprintf("%s:%d:%d: %s", e.Template, e.Line, e.Column, msg)
 }
 
-func renderTemplateForScopeToString(ctx context.Context, tc *scopedTemplateCache) func(name string, data interface{}) (string, error) {
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
 
-	return func(name string, data any) (string, error) {
-		_, span := tracer.Start(ctx, fmt.Sprintf("mustache.RenderTemplateToString %s", name),
-			trace.WithAttributes(
-				attribute.String("template", name),
-			),
-		)
+func templateError(name string, err error) error {
+	pe := &pongo2.Error{}
+	if !errors.As(err, &pe) || pe.OrigError == nil {
+		return err
+	}
 
-		defer span.End()
-		template, err := tc.getTemplate(name)
-		if err != nil {
-			span.RecordError(err)
-			return "", fmt.Errorf("could not get/parse template %s in scope %s: %w", name, tc.sp.scope, err)
-		}
+	te := &TemplateError{
+		Template: name,
+		Line:     pe.Line,
+		Column:   pe.Column,
+		Err:      pe,
+	}
 
-		return template.Render(data)
+	if pe.Filename != "" && pe.Filename != "<string>" {
+		te.Template = pe.Filename
 	}
 
+	return te
 }
 
-type templateCacheForPathFactory struct {
-	partials      map[string]string
-	cachesForPath map[string]*scopedTemplateCache
-	mu            *sync.Mutex
+// resolve returns the absolute name of the template file,
+// names are relative to the scope unless they start with `/`.
+func resolve(scope, name string) string {
+	if !strings.HasSuffix(name, ".pongo2") {
+		name = name + ".pongo2"
+	}
+
+	if !strings.HasPrefix(name, "/") {
+		name = path.Join(scope, name)
+	}
+
+	return path.Clean(name)
 }
 
-func (tf *templateCacheForPathFactory) getTemplateCacheForPath(pth string) *scopedTemplateCache {
-	tf.mu.Lock()
-	defer tf.mu.Unlock()
-	tc, found := tf.cachesForPath[pth]
-	if !found {
-		tc = &scopedTemplateCache{
-			cached: make(map[string]*mustache.Template),
-			sp:     scopedPartialProvider{partials: tf.partials, scope: pth},
-			mu:     &sync.RWMutex{},
-		}
-		tf.cachesForPath[pth] = tc
+func getTemplate(ts *pongo2.TemplateSet, scope, name string) (*pongo2.Template, string, error) {
+	fileName := resolve(scope, name)
+	template, err := ts.FromCache(fileName)
+	if err != nil {
+		return nil, fileName, fmt.Errorf("could not get/parse template %s in scope %s: %w", name, scope, templateError(fileName, err))
 	}
-	return tc
+	return template, fileName, nil
 }
 
-type scopedTemplateCache struct {
-	cached map[string]*mustache.Template
-	sp     scopedPartialProvider
-	mu     *sync.RWMutex
+// RenderOptions of pongo2 templates. There is no layout option, templates name their base with `{% extends %}`.
+type RenderOptions struct {
+	// Fragment is the name of a block, only the block is rendered, e.g. for htmx requests.
+	Fragment string `lean:"fragment"`
 }
 
-func (tc *scopedTemplateCache) getTemplate(name string) (*mustache.Template, error) {
-	tc.mu.RLock()
-	template, found := tc.cached[name]
-	if found {
-		tc.mu.RUnlock()
-		return template, nil
+// execute renders the template, or the block named by the fragment option.
+func execute(template *pongo2.Template, vals pongo2.Context, opts *RenderOptions, w io.Writer) error {
+	if opts == nil || opts.Fragment == "" {
+		return template.ExecuteWriter(vals, w)
 	}
-	tc.mu.RUnlock()
 
-	templateString, err := tc.sp.Get(name)
+	blocks, err := template.ExecuteBlocks(vals, []string{opts.Fragment})
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	template, err = mustache.ParseStringPartials(templateString, tc.sp)
-	if err != nil {
-		return nil, fmt.Errorf("could not parse template: %w", err)
+	block, found := blocks[opts.Fragment]
+	if !found {
+		return fmt.Errorf("there is no block %s", opts.Fragment)
 	}
 
-	tc.mu.Lock()
-	tc.cached[name] = template
-	tc.mu.Unlock()
-
-	return template, nil
+	_, err = io.WriteString(w, block)
+	return err
 }
 
-type scopedPartialProvider struct {
-	partials map[string]string
-	scope    string
+// withHelpers returns the context with the helpers it doesn't set itself.
+func withHelpers(vals pongo2.Context, helpers map[string]any) pongo2.Context {
+	if len(helpers) == 0 {
+		return vals
+	}
+
+	res := pongo2.Context{}
+	for k, v := range helpers {
+		res[k] = v
+	}
+	for k, v := range vals {
+		res[k] = v
+	}
+	return res
 }
 
-func (sp scopedPartialProvider) Get(name string) (string, error) {
-	if !strings.HasPrefix(name, "/") {
-		name = path.Join(sp.scope, name)
+func renderTemplateForScope(ctx context.Context, ts *pongo2.TemplateSet, scope string, helpers map[string]any, w io.Writer) func(name string, vals pongo2.Context, opts *RenderOptions) error {
+
+	return func(name string, vals pongo2.Context, opts *RenderOptions) error {
+		_, span := tracer.Start(ctx, fmt.Sprintf("pongo2.RenderTemplate %s", name),
+			trace.WithAttributes(
+				attribute.String("template", name),
+			),
+		)
+
+		defer span.End()
+
+		template, fileName, err := getTemplate(ts, scope, name)
+		if err != nil {
+			span.RecordError(err)
+			return err
+		}
+
+		err = execute(template, withHelpers(vals, helpers), opts, w)
+		if err != nil {
+			err = fmt.Errorf("could not render template %s: %w", name, templateError(fileName, err))
+			span.RecordError(err)
+			return err
+		}
+
+		return nil
 	}
-	name = path.Clean(name)
-	partial, found := sp.partials[name]
-	if !found {
-		return "", fmt.Errorf("could not find mustache partial %s", name)
+
+}
+
+func renderTemplateForScopeToString(ctx context.Context, ts *pongo2.TemplateSet, scope string, helpers map[string]any) func(name string, vals pongo2.Context, opts *RenderOptions) (string, error) {
+
+	return func(name string, vals pongo2.Context, opts *RenderOptions) (string, error) {
+		_, span := tracer.Start(ctx, fmt.Sprintf("pongo2.RenderTemplateToString %s", name),
+			trace.WithAttributes(
+				attribute.String("template", name),
+			),
+		)
+
+		defer span.End()
+
+		template, fileName, err := getTemplate(ts, scope, name)
+		if err != nil {
+			span.RecordError(err)
+			return "", err
+		}
+
+		buf := &bytes.Buffer{}
+		err = execute(template, withHelpers(vals, helpers), opts, buf)
+		if err != nil {
+			err = fmt.Errorf("could not render template %s: %w", name, templateError(fileName, err))
+			span.RecordError(err)
+			return "", err
+		}
+
+		return buf.String(), nil
 	}
-	return partial, nil
+
 }
@@ -0,0 +1,159 @@
+package pongo2
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/dop251/goja"
+	"github.com/flosch/pongo2/v6"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// errPending stops a render pass at a value whose promise isn't settled yet.
+var errPending = errors.New("value is pending")
+
+// streamState renders the template in passes on the event loop. Every pass renders the page up to the
+// first value that is still pending, writes what the previous passes haven't written and flushes it,
+// the next pass starts once the promise of the value is settled.
+type streamState struct {
+	rt       *goja.Runtime
+	w        http.ResponseWriter
+	name     string
+	fileName string
+	template *pongo2.Template
+	vals     pongo2.Context
+	written  []byte
+	waiting  *goja.Promise
+	resolve  func(any)
+	reject   func(any)
+}
+
+// pendingValue returns the function standing in for the promise in the context,
+// pongo2 calls functions it resolves.
+func (s *streamState) pendingValue(p *goja.Promise) func() (any, error) {
+	return func() (any, error) {
+		if p.State() == goja.PromiseStateFulfilled {
+			return p.Result().Export(), nil
+		}
+		s.waiting = p
+		return nil, errPending
+	}
+}
+
+func (s *streamState) next() {
+	s.waiting = nil
+
+	buf := &bytes.Buffer{}
+	err := s.template.ExecuteWriterUnbuffered(s.vals, buf)
+	if err != nil && s.waiting == nil {
+		s.reject(s.rt.NewGoError(fmt.Errorf("could not render template %s: %w", s.name, templateError(s.fileName, err))))
+		return
+	}
+
+	out := buf.Bytes()
+	if !bytes.HasPrefix(out, s.written) {
+		s.reject(s.rt.NewGoError(fmt.Errorf("could not stream template %s: the output has changed between passes", s.name)))
+		return
+	}
+
+	_, err = s.w.Write(out[len(s.written):])
+	if err != nil {
+		s.reject(s.rt.NewGoError(fmt.Errorf("could not write response: %w", err)))
+		return
+	}
+	s.written = out
+
+	err = http.NewResponseController(s.w).Flush()
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		s.reject(s.rt.NewGoError(fmt.Errorf("could not flush response: %w", err)))
+		return
+	}
+
+	if s.waiting == nil {
+		s.resolve(goja.Undefined())
+		return
+	}
+
+	if s.waiting.State() == goja.PromiseStateRejected {
+		s.reject(s.waiting.Result())
+		return
+	}
+
+	s.wait(s.waiting)
+}
+
+// wait continues once the promise is settled, the result is checked by the next pass.
+func (s *streamState) wait(p *goja.Promise) {
+	obj := s.rt.ToValue(p).ToObject(s.rt)
+	then, isFunction := goja.AssertFunction(obj.Get("then"))
+	if !isFunction {
+		s.reject(s.rt.NewTypeError("value is not a promise"))
+		return
+	}
+
+	settled := s.rt.ToValue(func(goja.Value) { s.next() })
+	_, err := then(obj, settled, settled)
+	if err != nil {
+		s.reject(err)
+	}
+}
+
+// streamTemplateForScope returns `stream(name, context)`. Values of the context can be promises, the page
+// is flushed up to every value that is still pending, e.g. the head of the base template and the blocks before it.
+func streamTemplateForScope(ctx context.Context, ts *pongo2.TemplateSet, scope string, helpers map[string]any, w http.ResponseWriter, rt *goja.Runtime) func(name string, data *goja.Object) (*goja.Promise, error) {
+	return func(name string, data *goja.Object) (*goja.Promise, error) {
+		_, span := tracer.Start(ctx, fmt.Sprintf("pongo2.StreamTemplate %s", name),
+			trace.WithAttributes(
+				attribute.String("template", name),
+			),
+		)
+
+		template, fileName, err := getTemplate(ts, scope, name)
+		if err != nil {
+			span.RecordError(err)
+			span.End()
+			return nil, err
+		}
+
+		promise, resolvePromise, rejectPromise := rt.NewPromise()
+
+		s := &streamState{
+			rt:       rt,
+			w:        w,
+			name:     name,
+			fileName: fileName,
+			template: template,
+			resolve: func(v any) {
+				span.End()
+				resolvePromise(v)
+			},
+			reject: func(reason any) {
+				span.RecordError(fmt.Errorf("could not stream template %s: %v", name, reason))
+				span.End()
+				rejectPromise(reason)
+			},
+		}
+
+		vals := pongo2.Context{}
+		if data != nil {
+			for _, k := range data.Keys() {
+				v := data.Get(k)
+				p, isPromise := v.Export().(*goja.Promise)
+				if isPromise {
+					vals[k] = s.pendingValue(p)
+					continue
+				}
+				vals[k] = v.Export()
+			}
+		}
+		s.vals = withHelpers(vals, helpers)
+
+		s.next()
+
+		return promise, nil
+	}
+}
@@ -3,9 +3,14 @@ package lean_test
 import (
 	"context"
 	"io/fs"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/draganm/go-lean"
+	"github.com/draganm/go-lean/common/limits"
+	"github.com/flosch/pongo2/v6"
 	"github.com/go-logr/logr/testr"
 	"github.com/stretchr/testify/require"
 )
@@ -26,3 +31,114 @@ func TestRenderingPongo2Templates(t *testing.T) {
 	require.HTTPBodyContains(w.ServeHTTP, "GET", "/pongo2", nil, "this is a pongo template I'm included bar")
 
 }
+
+func TestPongo2RenderToString(t *testing.T) {
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sfs, err := fs.Sub(simple, "fixtures/pongo2")
+	require.NoError(err)
+
+	w, err := lean.Construct(ctx, sfs, testr.New(t), map[string]any{})
+	require.NoError(err)
+
+	require.HTTPBodyContains(w.ServeHTTP, "GET", "/page", nil, "page lean <box>lean</box>")
+}
+
+func TestPongo2ErrorsIncludeLineNumbers(t *testing.T) {
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sfs, err := fs.Sub(simple, "fixtures/pongo2")
+	require.NoError(err)
+
+	w, err := lean.Construct(ctx, sfs, testr.New(t), map[string]any{})
+	require.NoError(err)
+
+	require.HTTPBodyContains(w.ServeHTTP, "GET", "/broken", nil, "could not get/parse template broken in scope /broken: /broken/broken.pongo2:2:")
+}
+
+type greetingTag struct{}
+
+func (greetingTag) Execute(ctx *pongo2.ExecutionContext, w pongo2.TemplateWriter) *pongo2.Error {
+	_, err := w.WriteString("hello")
+	if err != nil {
+		return ctx.Error(err.Error(), nil)
+	}
+	return nil
+}
+
+func parseGreeting(doc *pongo2.Parser, start *pongo2.Token, arguments *pongo2.Parser) (pongo2.INodeTag, *pongo2.Error) {
+	return greetingTag{}, nil
+}
+
+func shout(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	return pongo2.AsValue(strings.ToUpper(in.String()) + "!"), nil
+}
+
+func TestPongo2Filters(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sfs, err := fs.Sub(simple, "fixtures/pongo2")
+	require.NoError(t, err)
+
+	w, err := lean.Construct(ctx, sfs, testr.New(t), map[string]any{},
+		lean.WithPongo2Filter("shout", shout),
+		lean.WithPongo2Tag("greeting", parseGreeting),
+	)
+	require.NoError(t, err)
+
+	t.Run("Go and JavaScript filters", func(t *testing.T) {
+		require.HTTPBodyContains(t, w.ServeHTTP, "GET", "/filters", nil, "12.50 EUR 12.50 USD LEAN! hello")
+	})
+
+	t.Run("the same application can be constructed again", func(t *testing.T) {
+		again, err := lean.Construct(ctx, sfs, testr.New(t), map[string]any{},
+			lean.WithPongo2Filter("shout", shout),
+			lean.WithPongo2Tag("greeting", parseGreeting),
+		)
+		require.NoError(t, err)
+
+		require.HTTPBodyContains(t, again.ServeHTTP, "GET", "/filters", nil, "12.50 EUR 12.50 USD LEAN! hello")
+	})
+
+	t.Run("filters can't be registered with another function", func(t *testing.T) {
+		_, err := lean.Construct(ctx, sfs, testr.New(t), map[string]any{},
+			lean.WithPongo2Filter("shout", func(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+				return in, nil
+			}),
+			lean.WithPongo2Tag("greeting", parseGreeting),
+		)
+		require.ErrorContains(t, err, "filter shout is already registered with another function")
+	})
+
+	t.Run("tags can't be registered with another parser", func(t *testing.T) {
+		_, err := lean.Construct(ctx, sfs, testr.New(t), map[string]any{},
+			lean.WithPongo2Filter("shout", shout),
+			lean.WithPongo2Tag("greeting", func(doc *pongo2.Parser, start *pongo2.Token, arguments *pongo2.Parser) (pongo2.INodeTag, *pongo2.Error) {
+				return greetingTag{}, nil
+			}),
+		)
+		require.ErrorContains(t, err, "tag greeting is already registered with another parser")
+	})
+}
+
+func TestPongo2FilterTimeBudget(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sfs, err := fs.Sub(simple, "fixtures/pongo2limits")
+	require.NoError(t, err)
+
+	w, err := lean.Construct(ctx, sfs, testr.New(t), map[string]any{}, lean.WithLimits(limits.Limits{
+		TimeBudget: 100 * time.Millisecond,
+	}))
+	require.NoError(t, err)
+
+	require.HTTPStatusCode(t, w.ServeHTTP, "GET", "/spin", nil, http.StatusServiceUnavailable)
+}
@@ -2,8 +2,12 @@ package require
 
 import (
 	"fmt"
+	"path"
+	"sort"
+	"strings"
 
 	"github.com/dop251/goja"
+	"github.com/draganm/go-lean/common/compiler"
 )
 
 type Builder struct {
@@ -26,23 +30,231 @@ func (b *Builder) Consume(pth string, getContent func() ([]byte, error)) bool {
 	return false
 }
 
+// RequireProvider loads modules from `/lib`.
+// Relative module names are resolved from the file calling require().
+// Each runtime evaluates a module only once, subsequent calls return the cached exports.
 type RequireProvider func(rt *goja.Runtime, libName string) (goja.Value, error)
 
-func (b *Builder) Build() RequireProvider {
-	return func(rt *goja.Runtime, libName string) (goja.Value, error) {
-		libCodeGet, found := b.files[libName]
-		if !found {
-			return nil, fmt.Errorf("%s not found", libName)
+type moduleSource struct {
+	program *goja.Program
+	json    string
+}
+
+type moduleCache struct {
+	modules map[string]*goja.Object
+	loading []string
+}
+
+func getModuleCache(rt *goja.Runtime) (*moduleCache, error) {
+	global := rt.GlobalObject()
+	v := global.GetSymbol(cacheSymbol)
+	if v != nil {
+		mc, ok := v.Export().(*moduleCache)
+		if ok {
+			return mc, nil
 		}
+	}
 
-		libCode, err := libCodeGet()
+	mc := &moduleCache{
+		modules: map[string]*goja.Object{},
+	}
+
+	err := global.DefineDataPropertySymbol(cacheSymbol, rt.ToValue(mc), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE)
+	if err != nil {
+		return nil, fmt.Errorf("could not create module cache: %w", err)
+	}
+
+	return mc, nil
+}
+
+// Exports returns the exports of the modules rt has loaded, by module id.
+func Exports(rt *goja.Runtime) (map[string]goja.Value, error) {
+	mc, err := getModuleCache(rt)
+	if err != nil {
+		return nil, err
+	}
+
+	res := map[string]goja.Value{}
+	for id, module := range mc.modules {
+		res[id] = module.Get("exports")
+	}
+	return res, nil
+}
+
+// Forget removes the modules that are not kept from the cache of rt, so that the next require()
+// evaluates them again. It returns the ids of the removed modules.
+func Forget(rt *goja.Runtime, keep map[string]goja.Value) ([]string, error) {
+	mc, err := getModuleCache(rt)
+	if err != nil {
+		return nil, err
+	}
+
+	removed := []string{}
+	for id := range mc.modules {
+		_, kept := keep[id]
+		if !kept {
+			delete(mc.modules, id)
+			removed = append(removed, id)
+		}
+	}
+	sort.Strings(removed)
+
+	return removed, nil
+}
+
+func (b *Builder) Build() (RequireProvider, error) {
+	sources := map[string]*moduleSource{}
+
+	for pth, getContent := range b.files {
+		libCode, err := getContent()
+		if err != nil {
+			return nil, fmt.Errorf("could not get code of %s: %w", pth, err)
+		}
+
+		if path.Ext(pth) == ".json" {
+			sources[pth] = &moduleSource{json: string(libCode)}
+			continue
+		}
+
+		code, err := compiler.Transpile(pth, string(libCode), compiler.Options{
+			Banner: "(function(exports, module, require) {",
+			Footer: "})",
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		prog, err := goja.Compile(pth, code, false)
 		if err != nil {
-			return nil, fmt.Errorf("could not get code: %w", err)
+			return nil, fmt.Errorf("could not compile %s: %w", pth, err)
+		}
+
+		sources[pth] = &moduleSource{program: prog}
+	}
+
+	resolve := func(from, name string) (string, error) {
+		var candidate string
+
+		switch {
+		case strings.HasPrefix(name, "./"), strings.HasPrefix(name, "../"):
+			candidate = path.Join(path.Dir(from), name)
+		case strings.HasPrefix(name, "/"):
+			candidate = path.Clean(name)
+		default:
+			return "", fmt.Errorf("%s not found: module name must be absolute or start with ./ or ../", name)
+		}
+
+		candidates := []string{candidate}
+		for _, ext := range extensions {
+			candidates = append(candidates, candidate+ext)
+		}
+		for _, ext := range extensions {
+			candidates = append(candidates, path.Join(candidate, "index"+ext))
 		}
-		return rt.RunScript(
-			libName,
-			fmt.Sprintf(`(() => { var exports = {}; var module = { exports: exports}; %s; return module.exports})()`, libCode),
-		)
 
+		for _, c := range candidates {
+			_, found := sources[c]
+			if found {
+				return c, nil
+			}
+		}
+
+		return "", fmt.Errorf("%s not found (required from %s)", name, from)
+	}
+
+	var load func(rt *goja.Runtime, from, name string) (goja.Value, error)
+
+	load = func(rt *goja.Runtime, from, name string) (goja.Value, error) {
+		id, err := resolve(from, name)
+		if err != nil {
+			return nil, err
+		}
+
+		mc, err := getModuleCache(rt)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, l := range mc.loading {
+			if l == id {
+				cycle := append(append([]string{}, mc.loading[i:]...), id)
+				return nil, fmt.Errorf("circular dependency: %s", strings.Join(cycle, " -> "))
+			}
+		}
+
+		module, found := mc.modules[id]
+		if found {
+			return module.Get("exports"), nil
+		}
+
+		src := sources[id]
+
+		if src.program == nil {
+			parse, _ := goja.AssertFunction(rt.Get("JSON").ToObject(rt).Get("parse"))
+			exports, err := parse(nil, rt.ToValue(src.json))
+			if err != nil {
+				return nil, fmt.Errorf("could not parse %s: %w", id, err)
+			}
+
+			module = rt.NewObject()
+			module.Set("id", id)
+			module.Set("filename", id)
+			module.Set("exports", exports)
+			module.Set("loaded", true)
+			mc.modules[id] = module
+			return exports, nil
+		}
+
+		fn, err := rt.RunProgram(src.program)
+		if err != nil {
+			return nil, fmt.Errorf("could not evaluate %s: %w", id, err)
+		}
+
+		moduleFunction, isFunction := goja.AssertFunction(fn)
+		if !isFunction {
+			return nil, fmt.Errorf("%s did not evaluate to a module function", id)
+		}
+
+		exports := rt.NewObject()
+		module = rt.NewObject()
+		module.Set("id", id)
+		module.Set("filename", id)
+		module.Set("exports", exports)
+		module.Set("loaded", false)
+
+		moduleRequire := func(name string) (goja.Value, error) {
+			return load(rt, id, name)
+		}
+
+		mc.modules[id] = module
+		mc.loading = append(mc.loading, id)
+
+		_, err = moduleFunction(nil, exports, module, rt.ToValue(moduleRequire))
+
+		mc.loading = mc.loading[:len(mc.loading)-1]
+
+		if err != nil {
+			// failed modules are not cached, next require() will try again
+			delete(mc.modules, id)
+			return nil, err
+		}
+
+		module.Set("loaded", true)
+
+		return module.Get("exports"), nil
+	}
+
+	return func(rt *goja.Runtime, libName string) (goja.Value, error) {
+		return load(rt, callerFileName(rt), libName)
+	}, nil
+}
+
+// callerFileName returns the name of the script that called require()
+func callerFileName(rt *goja.Runtime) string {
+	for _, f := range rt.CaptureCallStack(0, nil) {
+		if f.SrcName() != "<native>" {
+			return f.SrcName()
+		}
 	}
+	return "/"
 }
@@ -2,6 +2,14 @@ package require
 
 import (
 	"regexp"
+
+	"github.com/dop251/goja"
 )
 
-var libRegexp = regexp.MustCompile(`^/lib/(.+).js$`)
+var libRegexp = regexp.MustCompile(`^/lib/(.+).(js|ts|json)$`)
+
+// extensions are tried in order when module is required without one
+var extensions = []string{".js", ".ts", ".json"}
+
+// modules loaded by a runtime are cached in a hidden property of its global object
+var cacheSymbol = goja.NewSymbol("lean.require.cache")
@@ -0,0 +1,137 @@
+package shared
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/draganm/go-lean/common/globals"
+)
+
+// Globals returns the `shared` global backed by the store:
+//
+//	shared.set("config", {enabled: true}, "10m")
+//	shared.get("config")                    // {enabled: true}, undefined when missing
+//	shared.incr("visits")                   // 1, 2, 3, ...
+//	shared.cas("lock", undefined, "me", 5000)  // true if the key has not existed
+//	shared.delete("lock")
+//	shared.keys()
+//
+// Values have to be JSON serialisable, CAS compares their JSON representation.
+// TTL is either a number of milliseconds or a duration string like "5s".
+func (s *Store) Globals() globals.Globals {
+	return globals.Globals{
+		"shared": s.provider,
+	}
+}
+
+func (s *Store) provider(rt *goja.Runtime) (globals.Values, error) {
+	js := rt.Get("JSON").ToObject(rt)
+
+	stringify, isFunction := goja.AssertFunction(js.Get("stringify"))
+	if !isFunction {
+		return nil, fmt.Errorf("JSON.stringify is not a function")
+	}
+
+	parse, isFunction := goja.AssertFunction(js.Get("parse"))
+	if !isFunction {
+		return nil, fmt.Errorf("JSON.parse is not a function")
+	}
+
+	toJSON := func(v goja.Value) (string, error) {
+		res, err := stringify(nil, v)
+		if err != nil {
+			return "", err
+		}
+		if goja.IsUndefined(res) {
+			return "", fmt.Errorf("value is not JSON serialisable")
+		}
+		return res.String(), nil
+	}
+
+	fromJSON := func(value string, found bool) (goja.Value, error) {
+		if !found {
+			return goja.Undefined(), nil
+		}
+		return parse(nil, rt.ToValue(value))
+	}
+
+	return globals.Values{
+		"get": func(key string) (goja.Value, error) {
+			return fromJSON(s.Get(key))
+		},
+		"set": func(key string, v goja.Value, ttl goja.Value) error {
+			d, err := parseTTL(ttl)
+			if err != nil {
+				return err
+			}
+			value, err := toJSON(v)
+			if err != nil {
+				return fmt.Errorf("could not set %s: %w", key, err)
+			}
+			s.Set(key, value, d)
+			return nil
+		},
+		"delete": s.Delete,
+		"incr": func(key string, delta goja.Value, ttl goja.Value) (float64, error) {
+			d, err := parseTTL(ttl)
+			if err != nil {
+				return 0, err
+			}
+			by := 1.0
+			if delta != nil && !goja.IsUndefined(delta) {
+				by = delta.ToFloat()
+			}
+			return s.Incr(key, by, d)
+		},
+		"cas": func(key string, old goja.Value, v goja.Value, ttl goja.Value) (bool, error) {
+			d, err := parseTTL(ttl)
+			if err != nil {
+				return false, err
+			}
+
+			var oldValue *string
+			if old != nil && !goja.IsUndefined(old) {
+				ov, err := toJSON(old)
+				if err != nil {
+					return false, fmt.Errorf("could not compare %s: %w", key, err)
+				}
+				oldValue = &ov
+			}
+
+			value, err := toJSON(v)
+			if err != nil {
+				return false, fmt.Errorf("could not set %s: %w", key, err)
+			}
+
+			return s.CompareAndSwap(key, oldValue, value, d), nil
+		},
+		"keys": func() []string {
+			keys := s.Keys()
+			sort.Strings(keys)
+			return keys
+		},
+	}, nil
+}
+
+func parseTTL(v goja.Value) (time.Duration, error) {
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return 0, nil
+	}
+
+	switch ttl := v.Export().(type) {
+	case string:
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			return 0, fmt.Errorf("could not parse ttl: %w", err)
+		}
+		return d, nil
+	case int64:
+		return time.Duration(ttl) * time.Millisecond, nil
+	case float64:
+		return time.Duration(ttl * float64(time.Millisecond)), nil
+	default:
+		return 0, fmt.Errorf("ttl must be a number of milliseconds or a duration string")
+	}
+}
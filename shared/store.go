@@ -0,0 +1,170 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// how often expired entries are removed
+const janitorInterval = time.Minute
+
+type entry struct {
+	value   string
+	expires time.Time
+}
+
+func (e entry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// Store is a concurrency-safe key/value store shared by all runtimes.
+// Values are stored as JSON, so that they can be read by any runtime.
+type Store struct {
+	mu      *sync.Mutex
+	entries map[string]entry
+	now     func() time.Time
+}
+
+// New creates a store. Expired entries are removed periodically
+// until the context is done.
+func New(ctx context.Context) *Store {
+	s := &Store{
+		mu:      &sync.Mutex{},
+		entries: map[string]entry{},
+		now:     time.Now,
+	}
+
+	go func() {
+		ticker := time.NewTicker(janitorInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.removeExpired()
+			}
+		}
+	}()
+
+	return s
+}
+
+func (s *Store) removeExpired() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for k, e := range s.entries {
+		if e.expired(now) {
+			delete(s.entries, k)
+		}
+	}
+}
+
+func (s *Store) expiry(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return s.now().Add(ttl)
+}
+
+// get has to be called with the lock held.
+func (s *Store) get(key string) (entry, bool) {
+	e, found := s.entries[key]
+	if !found {
+		return entry{}, false
+	}
+	if e.expired(s.now()) {
+		delete(s.entries, key)
+		return entry{}, false
+	}
+	return e, true
+}
+
+// Get returns the JSON value of the key.
+func (s *Store) Get(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, found := s.get(key)
+	return e.value, found
+}
+
+// Set sets the JSON value of the key. Zero ttl means the value doesn't expire.
+func (s *Store) Set(key, value string, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = entry{value: value, expires: s.expiry(ttl)}
+}
+
+// Delete removes the key and reports whether it has existed.
+func (s *Store) Delete(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, found := s.get(key)
+	delete(s.entries, key)
+	return found
+}
+
+// Incr atomically adds delta to the numeric value of the key and returns the result.
+// A missing key is created with the value of delta and the given ttl, the expiry
+// of an existing key is kept.
+func (s *Store) Incr(key string, delta float64, ttl time.Duration) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, found := s.get(key)
+	if !found {
+		s.entries[key] = entry{value: formatNumber(delta), expires: s.expiry(ttl)}
+		return delta, nil
+	}
+
+	v, err := strconv.ParseFloat(e.value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("value of %s is not a number", key)
+	}
+
+	v += delta
+	e.value = formatNumber(v)
+	s.entries[key] = e
+	return v, nil
+}
+
+// CompareAndSwap sets the JSON value of the key only if the current value equals old.
+// A nil old value expects the key not to exist.
+func (s *Store) CompareAndSwap(key string, old *string, value string, ttl time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, found := s.get(key)
+	if old == nil && found {
+		return false
+	}
+
+	if old != nil && (!found || e.value != *old) {
+		return false
+	}
+
+	s.entries[key] = entry{value: value, expires: s.expiry(ttl)}
+	return true
+}
+
+// Keys returns all keys that have not expired.
+func (s *Store) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	keys := []string{}
+	for k, e := range s.entries {
+		if !e.expired(now) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,63 @@
+package shared
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreExpiry(t *testing.T) {
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	now := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	s := New(ctx)
+	s.now = func() time.Time { return now }
+
+	s.Set("short", `"value"`, time.Second)
+	s.Set("forever", `1`, 0)
+
+	_, err := s.Incr("window", 1, 10*time.Second)
+	require.NoError(err)
+
+	now = now.Add(2 * time.Second)
+
+	_, found := s.Get("short")
+	require.False(found)
+
+	v, err := s.Incr("window", 1, 10*time.Second)
+	require.NoError(err)
+	require.Equal(2.0, v)
+
+	// expiry of an existing key is kept when incremented
+	now = now.Add(9 * time.Second)
+	v, err = s.Incr("window", 1, 10*time.Second)
+	require.NoError(err)
+	require.Equal(1.0, v)
+
+	s.removeExpired()
+	require.ElementsMatch([]string{"forever", "window"}, s.Keys())
+}
+
+func TestStoreCompareAndSwap(t *testing.T) {
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := New(ctx)
+
+	old := `"a"`
+	require.False(s.CompareAndSwap("k", &old, `"b"`, 0))
+	require.True(s.CompareAndSwap("k", nil, `"a"`, 0))
+	require.False(s.CompareAndSwap("k", nil, `"b"`, 0))
+	require.True(s.CompareAndSwap("k", &old, `"b"`, 0))
+
+	v, found := s.Get("k")
+	require.True(found)
+	require.Equal(`"b"`, v)
+}
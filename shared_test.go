@@ -0,0 +1,60 @@
+package lean_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/draganm/go-lean"
+	"github.com/draganm/go-lean/shared"
+	"github.com/go-logr/logr/testr"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSharedStore(t *testing.T) {
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sfs, err := fs.Sub(simple, "fixtures/shared")
+	require.NoError(err)
+
+	store := shared.New(ctx)
+
+	w, err := lean.Construct(ctx, sfs, testr.New(t), map[string]any{}, lean.WithSharedStore(store))
+	require.NoError(err)
+
+	for i := 0; i < 3; i++ {
+		require.HTTPStatusCode(w.ServeHTTP, "POST", "/visit", nil, 200)
+	}
+
+	visits, found := store.Get("visits")
+	require.True(found)
+	require.Equal("3", visits)
+
+	metrics := findMetrics(t, "shared_visits", dto.MetricType_GAUGE)
+	require.Len(metrics, 1)
+	require.Equal(3.0, metrics[0].GetGauge().GetValue())
+
+	rec := httptest.NewRecorder()
+	w.ServeHTTP(rec, httptest.NewRequest("GET", "/state", nil))
+	require.Equal(200, rec.Code)
+
+	res := map[string]any{}
+	require.NoError(json.Unmarshal(rec.Body.Bytes(), &res))
+	require.Equal(map[string]any{
+		"user":       map[string]any{"name": "jane", "roles": []any{"admin"}},
+		"missing":    true,
+		"acquired":   true,
+		"reacquired": false,
+		"swapped":    true,
+		"lock":       "third",
+		"deleted":    true,
+		"error":      "value of user is not a number",
+		"keys":       []any{"user", "visits"},
+	}, res)
+}
@@ -0,0 +1,106 @@
+package lean_test
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/draganm/go-lean"
+	"github.com/draganm/go-lean/common/eventloop"
+	"github.com/draganm/go-lean/common/limits"
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreaming(t *testing.T) {
+	sfs, err := fs.Sub(simple, "fixtures/streaming")
+	require.NoError(t, err)
+
+	for _, pth := range []string{"/page", "/pongo"} {
+		t.Run(pth, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			release := make(chan struct{})
+
+			w, err := lean.Construct(ctx, sfs, testr.New(t), map[string]any{
+				"fetchRows": func(loop *eventloop.EventLoop) *goja.Promise {
+					return loop.Promise(func() (any, error) {
+						<-release
+						return []string{"a", "b"}, nil
+					})
+				},
+			})
+			require.NoError(t, err)
+
+			s := httptest.NewServer(w)
+			defer s.Close()
+
+			res, err := http.Get(s.URL + pth)
+			require.NoError(t, err)
+			defer res.Body.Close()
+			require.Equal(t, http.StatusOK, res.StatusCode)
+
+			body := bufio.NewReader(res.Body)
+
+			// the layout head and the page up to the pending rows are flushed before the rows are fetched
+			head := "<html><title>Slow</title><h1>Slow</h1><ul>"
+			buf := make([]byte, len(head))
+			_, err = io.ReadFull(body, buf)
+			require.NoError(t, err)
+			require.Equal(t, head, string(buf))
+
+			close(release)
+
+			rest, err := io.ReadAll(body)
+			require.NoError(t, err)
+			require.Equal(t, "<li>a</li><li>b</li></ul><footer>end</footer></html>", strings.TrimSpace(string(rest)))
+		})
+	}
+}
+
+func TestPongo2StreamingStopsWithTheInvocation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sfs, err := fs.Sub(simple, "fixtures/streaming")
+	require.NoError(t, err)
+
+	w, err := lean.Construct(ctx, sfs, testr.New(t), map[string]any{}, lean.WithLimits(limits.Limits{
+		TimeBudget: 100 * time.Millisecond,
+	}))
+	require.NoError(t, err)
+
+	s := httptest.NewServer(w)
+	defer s.Close()
+
+	t.Run("rejected values reject the stream", func(t *testing.T) {
+		res, err := http.Get(s.URL + "/pongo/rejected")
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, "<html><title>Rejected</title><h1>Rejected</h1><ul> rejected: no rows", string(body))
+	})
+
+	t.Run("the render ends with the time budget", func(t *testing.T) {
+		start := time.Now()
+		res, err := http.Get(s.URL + "/pongo/never")
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(string(body), "<html><title>Never</title><h1>Never</h1><ul>"))
+		require.NotContains(t, string(body), "</ul>")
+		require.Less(t, time.Since(start), 5*time.Second)
+	})
+}
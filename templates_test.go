@@ -0,0 +1,54 @@
+package lean_test
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/draganm/go-lean"
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStandaloneTemplates(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sfs, err := fs.Sub(simple, "fixtures/templates")
+	require.NoError(t, err)
+
+	reported := make(chan string, 10)
+
+	w, err := lean.Construct(ctx, sfs, testr.New(t), map[string]any{
+		"report": func(s string) {
+			reported <- s
+		},
+	})
+	require.NoError(t, err)
+
+	t.Run("cron jobs render templates relative to /templates", func(t *testing.T) {
+		select {
+		case v := <-reported:
+			require.Equal(t, "Welcome cron!\n2 jobs\n3 in total", v)
+		case <-time.After(3 * time.Second):
+			require.Fail(t, "cron job has not reported")
+		}
+	})
+
+	t.Run("handlers render templates by their absolute path without the layouts of pages", func(t *testing.T) {
+		require.HTTPBodyContains(t, w.ServeHTTP, "GET", "/email", nil, "Welcome web!")
+		require.HTTPBodyNotContains(t, w.ServeHTTP, "GET", "/email", nil, "<html>")
+	})
+}
+
+func TestStandaloneTemplatesCollidingWithPages(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sfs, err := fs.Sub(simple, "fixtures/templatecollision")
+	require.NoError(t, err)
+
+	_, err = lean.Construct(ctx, sfs, testr.New(t), nil)
+	require.ErrorContains(t, err, "have the same name /templates/a.mustache")
+}
@@ -0,0 +1,276 @@
+package lean
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/draganm/go-lean/common/globals"
+	"github.com/draganm/go-lean/common/goja/fieldmapper"
+	"github.com/draganm/go-lean/common/tsdecl"
+)
+
+// builtinModules documents globals that are set by lean itself.
+var builtinModules = []globals.Module{
+	{
+		Name:        "require",
+		Doc:         "Loads a module from `/lib`, by an absolute path or relative to the calling file.",
+		Declaration: "(name: string) => any",
+	},
+	{
+		Name: "mustache",
+		Doc:  "Renders `.mustache` templates next to the handler or in its parent directories.\nOutside of handlers names are relative to `/templates`.",
+		Declaration: `{
+  /** Renders the template to the response, in the nearest _layout template unless another layout is given. Only available in handlers. */
+  render(name: string, data?: any, options?: { layout?: string | false, fragment?: string }): void
+  /** Renders the template and returns the result. */
+  renderToString(name: string, data?: any, options?: { layout?: string | false, fragment?: string }): string
+  /**
+   * Renders the template to the response part by part, flushing each part as soon as the promises
+   * in data it uses are resolved. Only available in handlers.
+   */
+  stream(name: string, data?: Record<string, any>, options?: { layout?: string | false }): Promise<void>
+}`,
+	},
+	{
+		Name: "pongo2",
+		Doc:  "Renders `.pongo2` templates, names are relative to the handler unless they start with `/`.\nOutside of handlers names are relative to `/templates`.",
+		Declaration: `{
+  /** Renders the template to the response. Only available in handlers. */
+  render(name: string, context?: Record<string, any>, options?: { fragment?: string }): void
+  /** Renders the template and returns the result. */
+  renderToString(name: string, context?: Record<string, any>, options?: { fragment?: string }): string
+  /**
+   * Renders the template to the response while promises in the context are pending, flushing
+   * the page up to the first pending value the template uses until all are settled. Only available in handlers.
+   */
+  stream(name: string, context?: Record<string, any>): Promise<void>
+}`,
+	},
+	{
+		Name: "gotemplate",
+		Doc:  "Renders `.gohtml` templates with html/template, names are relative to the handler unless they start with `/`.\nOutside of handlers names are relative to `/templates`.",
+		Declaration: `{
+  /** Renders the template to the response, in the nearest _layout template unless another layout is given. Only available in handlers. */
+  render(name: string, data?: any, options?: { layout?: string | false, fragment?: string }): void
+  /** Renders the template and returns the result. */
+  renderToString(name: string, data?: any, options?: { layout?: string | false, fragment?: string }): string
+}`,
+	},
+	{
+		Name: "markdown",
+		Doc:  "Renders GitHub Flavored Markdown.",
+		Declaration: `{
+  /** Returns the HTML of the Markdown, raw HTML in it is omitted. */
+  render(src: string): string
+}`,
+	},
+	{
+		Name:        "t",
+		Doc:         "Translates the key to the negotiated locale, or the default locale outside of handlers.\n`{name}` placeholders are replaced by args, `count` selects the plural form. Set when `/i18n` has catalogs.",
+		Declaration: "(key: string, args?: Record<string, any>) => string",
+	},
+	{
+		Name: "i18n",
+		Doc:  "Locales of the `/i18n` catalogs. Set when `/i18n` has catalogs.",
+		Declaration: `{
+  /** The negotiated locale, or the default locale outside of handlers. */
+  locale: string
+  locales: string[]
+  translate(locale: string, key: string, args?: Record<string, any>): string
+}`,
+	},
+	{
+		Name: "mail",
+		Doc:  "Sends email, bodies can be rendered from templates under `/templates`. Set when `lean.WithMail` is used.",
+		Declaration: `{
+  /** Delivers the message, retrying failed attempts, and resolves to its Message-ID. */
+  send(message: {
+    /** Defaults to the sender of the options. */
+    from?: string
+    to?: string | string[]
+    cc?: string | string[]
+    bcc?: string | string[]
+    replyTo?: string
+    subject?: string
+    text?: string
+    html?: string
+    /** Template of the text body, e.g. emails/welcome.text.mustache. */
+    textTemplate?: string
+    /** Template of the HTML body, the extension selects the engine. */
+    htmlTemplate?: string
+    /** Data the templates are rendered with. */
+    data?: any
+    /** Additional headers, e.g. List-Unsubscribe. Headers set from the other fields can't be set. */
+    headers?: Record<string, string>
+    attachments?: { filename: string, contentType?: string, content: string | ArrayBuffer }[]
+  }): Promise<string>
+}`,
+	},
+	{
+		Name: "htmx",
+		Doc:  "Headers of htmx requests and response headers htmx acts on, set them before writing the body.\n\nAvailable in: web.",
+		Declaration: `{
+  /** Whether the request was made by htmx. */
+  isRequest: boolean
+  isBoosted: boolean
+  isHistoryRestore: boolean
+  currentURL: string
+  /** Id of the target element. */
+  target: string
+  /** Id of the triggered element. */
+  trigger: string
+  triggerName: string
+  prompt: string
+  redirect(url: string): void
+  location(url: string): void
+  pushURL(url: string): void
+  replaceURL(url: string): void
+  reswap(swap: string): void
+  retarget(selector: string): void
+  reselect(selector: string): void
+  refresh(): void
+  /** Triggers events on the client, an event name or an object of event names and details. */
+  triggerEvents(events: string | Record<string, any>): void
+  triggerAfterSwap(events: string | Record<string, any>): void
+  triggerAfterSettle(events: string | Record<string, any>): void
+  /** Wraps the HTML in an element swapped out of band into the element with the id. */
+  oob(id: string, html: string, options?: { swap?: string, tag?: string }): string
+}`,
+	},
+	{
+		Name:        "sendServerEvents",
+		Doc:         "Streams server-sent events to the response until nextEvent throws.\n\nAvailable in: web.",
+		Declaration: "(nextEvent: () => { id?: string, event?: string, data?: string }) => void",
+	},
+	{
+		Name:        "returnStatus",
+		Doc:         "Ends the handler with the status code and message.\n\nAvailable in: web.",
+		Declaration: "(code: number, message: string) => never",
+	},
+	{
+		Name: "log",
+		Doc:  "Logs to the logger of the handler, cron job or metric. Objects are flattened into dotted keys,\nother arguments are key/value pairs.",
+		Declaration: `{
+  debug(message: string, ...args: any[]): void
+  info(message: string, ...args: any[]): void
+  warn(message: string, ...args: any[]): void
+  error(message: string, ...args: any[]): void
+  Info(message: string, ...keysAndValues: any[]): void
+  Error(err: any, message: string, ...keysAndValues: any[]): void
+  Enabled(): boolean
+  /** Returns a logger logging with the verbosity increased by level. */
+  V(level: number): typeof log
+  /** Returns a logger adding the key/value pairs to every message. */
+  WithValues(...keysAndValues: any[]): typeof log
+  /** Returns a logger with the name appended to its name. */
+  WithName(name: string): typeof log
+}`,
+	},
+	{
+		Name: "shared",
+		Doc:  "Key/value store shared by all runtimes. Values have to be JSON serialisable,\nTTLs are milliseconds or duration strings like `\"5s\"`.",
+		Declaration: `{
+  /** Returns the value, undefined when it is missing or has expired. */
+  get(key: string): any
+  set(key: string, value: any, ttl?: number | string): void
+  /** Reports whether the key has existed. */
+  delete(key: string): boolean
+  /** Atomically adds delta (1 by default), the TTL is only set when the key is created. */
+  incr(key: string, delta?: number, ttl?: number | string): number
+  /** Sets the value only if the current one equals old, undefined expects the key not to exist. */
+  cas(key: string, old: any, value: any, ttl?: number | string): boolean
+  keys(): string[]
+}`,
+	},
+	{Name: "setTimeout", Declaration: "(fn: (...args: any[]) => void, delay?: number, ...args: any[]) => number"},
+	{Name: "setInterval", Declaration: "(fn: (...args: any[]) => void, delay?: number, ...args: any[]) => number"},
+	{Name: "setImmediate", Declaration: "(fn: (...args: any[]) => void, ...args: any[]) => number"},
+	{Name: "clearTimeout", Declaration: "(id: number) => void"},
+	{Name: "clearInterval", Declaration: "(id: number) => void"},
+}
+
+var consoleModule = globals.Module{
+	Name: "console",
+	Doc:  "Logs to the logger of the handler, cron job or metric.",
+	Declaration: `{
+  log(...args: any[]): void
+  info(...args: any[]): void
+  debug(...args: any[]): void
+  warn(...args: any[]): void
+  error(...args: any[]): void
+}`,
+}
+
+// TypeScriptDeclarations returns the content of a `lean.d.ts` file declaring the built-in globals,
+// the globals passed to Construct and the modules registered with WithModules, so that editors
+// can complete them. Names of Go fields and methods follow WithCamelCase.
+func TypeScriptDeclarations(globs map[string]any, opts ...Option) (string, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	mods := append([]globals.Module{}, builtinModules...)
+	if o.nodeCompat != nil {
+		mods = append(mods, consoleModule)
+	}
+
+	names := make([]string, 0, len(globs))
+	for k := range globs {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		mods = append(mods, globals.Module{Name: name, Value: globs[name]})
+	}
+
+	mods = append(mods, o.modules...)
+
+	seen := map[string]bool{}
+	for _, m := range mods {
+		if seen[m.Name] {
+			return "", fmt.Errorf("global %s is declared twice", m.Name)
+		}
+		seen[m.Name] = true
+	}
+
+	in := injectables()
+	gen := tsdecl.New(fieldmapper.FallbackFieldMapper{CamelCase: o.camelCase})
+
+	decls := []string{}
+	for _, m := range mods {
+		if m.Declaration != "" {
+			decls = append(decls, gen.Declare(m, 0))
+			continue
+		}
+
+		contexts, err := in.UsableIn(m.Value)
+		if err != nil {
+			return "", fmt.Errorf("global %s: %w", m.Name, err)
+		}
+
+		if len(contexts) == 0 {
+			return "", fmt.Errorf("global %s can't be used in any context", m.Name)
+		}
+
+		if len(contexts) < len(in) {
+			m.Doc = strings.TrimSpace(m.Doc + "\n\nAvailable in: " + strings.Join(contexts, ", ") + ".")
+		}
+
+		decls = append(decls, gen.Declare(m, in.Injected(m.Value, contexts[0])))
+	}
+
+	sb := &strings.Builder{}
+	sb.WriteString("// Code generated by lean.TypeScriptDeclarations. DO NOT EDIT.\n\n")
+	sb.WriteString(strings.Join(decls, "\n"))
+
+	interfaces := gen.Interfaces()
+	if interfaces != "" {
+		sb.WriteString("\n")
+		sb.WriteString(interfaces)
+	}
+
+	return sb.String(), nil
+}
@@ -0,0 +1,85 @@
+package lean_test
+
+import (
+	"context"
+	"io/fs"
+	"net/http"
+	"testing"
+
+	"github.com/draganm/go-lean"
+	"github.com/draganm/go-lean/common/globals"
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+)
+
+type greeter struct {
+	Greeting string
+}
+
+func (g *greeter) Greet(name string) (string, error) {
+	return g.Greeting + " " + name, nil
+}
+
+type counter struct {
+	Count int `lean:"count"`
+}
+
+func TestTypeScriptDeclarations(t *testing.T) {
+	modules := lean.WithModules(
+		globals.Module{
+			Name:  "greeter",
+			Doc:   "Greets people.",
+			Value: &greeter{Greeting: "hello"},
+			Members: map[string]string{
+				"greet": "Returns the greeting for the name.",
+			},
+		},
+		globals.Module{
+			Name: "counter",
+			Doc:  "Counts requests.",
+			Value: func(r *http.Request) globals.Values {
+				return globals.Values{"count": 1}
+			},
+			Type: globals.TypeOf[*counter](),
+		},
+	)
+
+	t.Run("declares builtins, globals and modules", func(t *testing.T) {
+		require := require.New(t)
+
+		decls, err := lean.TypeScriptDeclarations(map[string]any{
+			"double": func(ctx context.Context, v int) int {
+				return v * 2
+			},
+		}, modules, lean.WithCamelCase())
+		require.NoError(err)
+
+		require.Contains(decls, "// Code generated by lean.TypeScriptDeclarations. DO NOT EDIT.")
+		require.Contains(decls, "declare const mustache: {")
+		require.Contains(decls, "declare const returnStatus: (code: number, message: string) => never")
+		require.Contains(decls, "declare const log: {")
+		require.Contains(decls, "declare function double(arg1: number): number")
+		require.Contains(decls, "/**\n * Greets people.\n */\ndeclare const greeter: {\n  greeting: string\n  /**\n   * Returns the greeting for the name.\n   */\n  greet(arg1: string): string\n}")
+		require.Contains(decls, " * Counts requests.\n *\n * Available in: web.\n */\ndeclare const counter: {\n  count: number\n}")
+	})
+
+	t.Run("modules are available as globals", func(t *testing.T) {
+		require := require.New(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		sfs, err := fs.Sub(simple, "fixtures/typescript")
+		require.NoError(err)
+
+		w, err := lean.Construct(ctx, sfs, testr.New(t), map[string]any{}, modules)
+		require.NoError(err)
+
+		require.HTTPBodyContains(w.ServeHTTP, "GET", "/greet", nil, "hello lean 1")
+	})
+
+	t.Run("modules can't be registered twice", func(t *testing.T) {
+		_, err := lean.TypeScriptDeclarations(map[string]any{"greeter": 1}, modules)
+		require.ErrorContains(t, err, "global greeter is declared twice")
+	})
+}
@@ -12,6 +12,8 @@ import (
 	"time"
 
 	"github.com/draganm/go-lean/common/globals"
+	"github.com/draganm/go-lean/common/jsruntime"
+	"github.com/draganm/go-lean/web/htmx"
 	"github.com/draganm/go-lean/web/jshandler"
 	"github.com/draganm/go-lean/web/sse"
 	"github.com/go-chi/chi/v5"
@@ -21,6 +23,7 @@ import (
 type jsHandlerInfo struct {
 	method     string
 	path       string
+	fileName   string
 	getContent func() ([]byte, error)
 }
 
@@ -41,6 +44,7 @@ func (b *Builder) Consume(pth string, getContent func() ([]byte, error)) bool {
 		return false
 	}
 
+	fullPath := pth
 	pth = strings.TrimPrefix(pth, "/web")
 
 	_, fileName := path.Split(pth)
@@ -51,6 +55,7 @@ func (b *Builder) Consume(pth string, getContent func() ([]byte, error)) bool {
 		b.jsHandlers = append(b.jsHandlers, jsHandlerInfo{
 			method:     method,
 			path:       pth,
+			fileName:   fullPath,
 			getContent: getContent,
 		})
 
@@ -61,20 +66,25 @@ func (b *Builder) Consume(pth string, getContent func() ([]byte, error)) bool {
 	return true
 }
 
+// Extras are the globals only set in handlers.
+var Extras = globals.Globals{
+	"sendServerEvents": sse.SSEProvider,
+	"returnStatus":     jshandler.ReturnStatus,
+	"htmx":             htmx.Provider,
+}
+
 func (b *Builder) Create(
 	log logr.Logger,
-	globs map[string]any,
+	factory *jsruntime.Factory,
+	opts jshandler.Options,
+	middlewares ...func(http.Handler) http.Handler,
 ) (*chi.Mux, error) {
 	r := chi.NewMux()
+	r.Use(middlewares...)
 
-	gl := globals.Globals{
-		"sendServerEvents": sse.SSEProvider,
-	}
-
-	var err error
-	gl, err = gl.Merge(globs)
+	env, err := factory.Environment("web", Extras)
 	if err != nil {
-		return nil, fmt.Errorf("could not merge globals: %w", err)
+		return nil, err
 	}
 
 	// TODO: check for overlapping handlers
@@ -91,8 +101,10 @@ func (b *Builder) Create(
 		handler, err := jshandler.New(
 			log,
 			jh.path,
+			jh.fileName,
 			string(data),
-			gl,
+			env,
+			opts,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("could not create js handler: %w", err)
@@ -0,0 +1,92 @@
+package htmx
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+
+	"github.com/draganm/go-lean/common/globals"
+)
+
+// OOBOptions are passed as the optional third argument of `htmx.oob`.
+type OOBOptions struct {
+	// Swap is the value of hx-swap-oob, defaults to `true`, e.g. `beforeend`.
+	Swap string `lean:"swap"`
+
+	// Tag is the element wrapping the content, defaults to `div`, e.g. `tr`.
+	Tag string `lean:"tag"`
+}
+
+// triggerHeader sets an HX-Trigger header, events are an event name or
+// an object of event names and details.
+func triggerHeader(w http.ResponseWriter, header string, events any) error {
+	switch e := events.(type) {
+	case string:
+		w.Header().Set(header, e)
+	default:
+		data, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("could not encode events: %w", err)
+		}
+		w.Header().Set(header, string(data))
+	}
+	return nil
+}
+
+// Provider provides the `htmx` global of handlers, describing htmx requests and setting
+// the response headers htmx acts on. Headers have to be set before the body is written.
+func Provider(r *http.Request, w http.ResponseWriter) globals.Values {
+	header := func(name string) func(value string) {
+		return func(value string) {
+			w.Header().Set(name, value)
+		}
+	}
+
+	return globals.Values{
+		"isRequest":        r.Header.Get("HX-Request") == "true",
+		"isBoosted":        r.Header.Get("HX-Boosted") == "true",
+		"isHistoryRestore": r.Header.Get("HX-History-Restore-Request") == "true",
+		"currentURL":       r.Header.Get("HX-Current-URL"),
+		"target":           r.Header.Get("HX-Target"),
+		"trigger":          r.Header.Get("HX-Trigger"),
+		"triggerName":      r.Header.Get("HX-Trigger-Name"),
+		"prompt":           r.Header.Get("HX-Prompt"),
+
+		"redirect":   header("HX-Redirect"),
+		"location":   header("HX-Location"),
+		"pushURL":    header("HX-Push-Url"),
+		"replaceURL": header("HX-Replace-Url"),
+		"reswap":     header("HX-Reswap"),
+		"retarget":   header("HX-Retarget"),
+		"reselect":   header("HX-Reselect"),
+		"refresh": func() {
+			w.Header().Set("HX-Refresh", "true")
+		},
+		"triggerEvents": func(events any) error {
+			return triggerHeader(w, "HX-Trigger", events)
+		},
+		"triggerAfterSwap": func(events any) error {
+			return triggerHeader(w, "HX-Trigger-After-Swap", events)
+		},
+		"triggerAfterSettle": func(events any) error {
+			return triggerHeader(w, "HX-Trigger-After-Settle", events)
+		},
+		"oob": OOB,
+	}
+}
+
+// OOB wraps the HTML in an element swapped out of band into the element with the id,
+// e.g. `<div id="cart-count" hx-swap-oob="true">3</div>`.
+func OOB(id, content string, opts *OOBOptions) string {
+	swap := "true"
+	tag := "div"
+	if opts != nil && opts.Swap != "" {
+		swap = opts.Swap
+	}
+	if opts != nil && opts.Tag != "" {
+		tag = html.EscapeString(opts.Tag)
+	}
+
+	return fmt.Sprintf(`<%s id="%s" hx-swap-oob="%s">%s</%s>`, tag, html.EscapeString(id), html.EscapeString(swap), content, tag)
+}
@@ -0,0 +1,206 @@
+package jshandler
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/go-logr/logr"
+)
+
+// Authenticator decides if a request may be served by a handler that
+// has `requireAuth` set in its config. Returning an error rejects the request
+// with 401.
+type Authenticator func(r *http.Request) error
+
+// Options are shared by all handlers, the config of each handler is read from its script.
+type Options struct {
+	// Authenticator authenticates requests to handlers with `requireAuth` set.
+	Authenticator Authenticator
+
+	// DevMode renders an HTML page with the JavaScript stack
+	// and source excerpt when a handler fails.
+	DevMode bool
+
+	// SourceLookup returns the original source of a file for
+	// the error page rendered in DevMode.
+	SourceLookup func(fileName string) (string, bool)
+
+	// Isolation defines how the global state of pooled runtimes
+	// is treated between requests.
+	Isolation Isolation
+
+	// LogGlobalMutations logs globals added, changed or deleted,
+	// and top-level bindings reassigned by every request.
+	LogGlobalMutations bool
+}
+
+// Config is read from the `config` global of the handler script once,
+// when the handler is created.
+type Config struct {
+	Timeout             string   `lean:"timeout"`
+	MaxBodySize         int64    `lean:"maxBodySize"`
+	AllowedContentTypes []string `lean:"allowedContentTypes"`
+	RequireAuth         bool     `lean:"requireAuth"`
+	CacheControl        string   `lean:"cacheControl"`
+	MaxConcurrency      int      `lean:"maxConcurrency"`
+
+	// QueueTimeout is the longest time a request waits for one of the MaxConcurrency slots.
+	// Without it, requests wait until the timeout or until the client disconnects.
+	QueueTimeout string `lean:"queueTimeout"`
+
+	timeout      time.Duration
+	queueTimeout time.Duration
+	semaphore    chan struct{}
+}
+
+func readConfig(rt *goja.Runtime, opts Options) (*Config, error) {
+	cfg := &Config{}
+
+	v := rt.Get("config")
+	if v != nil && !goja.IsUndefined(v) && !goja.IsNull(v) {
+		err := rt.ExportTo(v, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("could not read handler config: %w", err)
+		}
+	}
+
+	if cfg.Timeout != "" {
+		d, err := time.ParseDuration(cfg.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse timeout %q: %w", cfg.Timeout, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("timeout must be positive, got %s", cfg.Timeout)
+		}
+		cfg.timeout = d
+	}
+
+	if cfg.QueueTimeout != "" {
+		d, err := time.ParseDuration(cfg.QueueTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse queueTimeout %q: %w", cfg.QueueTimeout, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("queueTimeout must be positive, got %s", cfg.QueueTimeout)
+		}
+		cfg.queueTimeout = d
+	}
+
+	if cfg.MaxBodySize < 0 {
+		return nil, fmt.Errorf("maxBodySize must not be negative")
+	}
+
+	for i, ct := range cfg.AllowedContentTypes {
+		mt, _, err := mime.ParseMediaType(ct)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse allowed content type %q: %w", ct, err)
+		}
+		cfg.AllowedContentTypes[i] = mt
+	}
+
+	if cfg.RequireAuth && opts.Authenticator == nil {
+		return nil, fmt.Errorf("handler requires auth, but no authenticator is configured")
+	}
+
+	if cfg.MaxConcurrency < 0 {
+		return nil, fmt.Errorf("maxConcurrency must not be negative")
+	}
+
+	if cfg.MaxConcurrency > 0 {
+		cfg.semaphore = make(chan struct{}, cfg.MaxConcurrency)
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) isContentTypeAllowed(r *http.Request) bool {
+	if len(c.AllowedContentTypes) == 0 {
+		return true
+	}
+
+	// requests without body don't have a content type to check
+	if r.ContentLength == 0 || r.Body == nil || r.Body == http.NoBody {
+		return true
+	}
+
+	mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+
+	for _, act := range c.AllowedContentTypes {
+		if strings.EqualFold(act, mt) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// wrap enforces the config around the handler.
+// Timeout is only set on the request context, the handler itself is responsible
+// for interrupting the runtime once the context is done.
+func (c *Config) wrap(opts Options, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log := logr.FromContextOrDiscard(r.Context())
+
+		if c.timeout > 0 {
+			ctx, cancel := context.WithTimeout(r.Context(), c.timeout)
+			defer cancel()
+			r = r.WithContext(ctx)
+		}
+
+		if c.RequireAuth {
+			err := opts.Authenticator(r)
+			if err != nil {
+				log.V(1).Info("request not authenticated", "reason", err.Error())
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+
+		if !c.isContentTypeAllowed(r) {
+			http.Error(w, "unsupported media type", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		if c.MaxBodySize > 0 {
+			if r.ContentLength > c.MaxBodySize {
+				http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, c.MaxBodySize)
+		}
+
+		if c.semaphore != nil {
+			var queueTimeout <-chan time.Time
+			if c.queueTimeout > 0 {
+				t := time.NewTimer(c.queueTimeout)
+				defer t.Stop()
+				queueTimeout = t.C
+			}
+
+			select {
+			case c.semaphore <- struct{}{}:
+				defer func() { <-c.semaphore }()
+			case <-queueTimeout:
+				http.Error(w, "too many concurrent requests", http.StatusServiceUnavailable)
+				return
+			case <-r.Context().Done():
+				http.Error(w, "too many concurrent requests", http.StatusServiceUnavailable)
+				return
+			}
+		}
+
+		if c.CacheControl != "" {
+			w.Header().Set("Cache-Control", c.CacheControl)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
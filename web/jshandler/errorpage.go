@@ -0,0 +1,114 @@
+package jshandler
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/draganm/go-lean/common/jsstack"
+)
+
+// number of lines shown before and after the failing line
+const excerptContext = 5
+
+var errorPageTemplate = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Handler error</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+pre { background: #f6f6f6; padding: 1em; overflow-x: auto; }
+.line { display: block; }
+.failing { background: #ffd7d7; font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>Handler error</h1>
+<pre>{{ .Error }}</pre>
+{{ with .Excerpt }}
+<h2>{{ .File }}:{{ .Line }}</h2>
+<pre>{{ range .Lines }}<span class="line{{ if .Failing }} failing{{ end }}">{{ printf "%4d" .Number }} | {{ .Text }}</span>{{ end }}</pre>
+{{ end }}
+{{ with .Frames }}
+<h2>Stack</h2>
+<pre>{{ range . }}at {{ . }}
+{{ end }}</pre>
+{{ end }}
+</body>
+</html>
+`))
+
+type excerptLine struct {
+	Number  int
+	Text    string
+	Failing bool
+}
+
+type excerpt struct {
+	File  string
+	Line  int
+	Lines []excerptLine
+}
+
+func sourceExcerpt(frames []jsstack.Frame, lookup func(string) (string, bool)) *excerpt {
+	if lookup == nil {
+		return nil
+	}
+
+	for _, f := range frames {
+		src, found := lookup(f.File)
+		if !found {
+			continue
+		}
+
+		lines := strings.Split(src, "\n")
+		if f.Line < 1 || f.Line > len(lines) {
+			continue
+		}
+
+		e := &excerpt{File: f.File, Line: f.Line}
+
+		from := f.Line - excerptContext
+		if from < 1 {
+			from = 1
+		}
+
+		to := f.Line + excerptContext
+		if to > len(lines) {
+			to = len(lines)
+		}
+
+		for i := from; i <= to; i++ {
+			e.Lines = append(e.Lines, excerptLine{
+				Number:  i,
+				Text:    lines[i-1],
+				Failing: i == f.Line,
+			})
+		}
+
+		return e
+	}
+
+	return nil
+}
+
+func writeErrorPage(w http.ResponseWriter, err error, frames []jsstack.Frame, lookup func(string) (string, bool)) {
+	message := err.Error()
+
+	// position of the exception is already part of the stack
+	exc := &goja.Exception{}
+	if errors.As(err, &exc) {
+		message = exc.Value().String()
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusInternalServerError)
+	errorPageTemplate.Execute(w, map[string]any{
+		"Error":   message,
+		"Frames":  frames,
+		"Excerpt": sourceExcerpt(frames, lookup),
+	})
+}
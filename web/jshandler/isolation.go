@@ -0,0 +1,276 @@
+package jshandler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/draganm/go-lean/common/compiler"
+	"github.com/draganm/go-lean/require"
+)
+
+// Isolation defines how the global state of a pooled runtime
+// is treated between requests.
+type Isolation int
+
+const (
+	// IsolationNone only removes the globals provided for the request.
+	// Anything else a handler changes is seen by the following requests
+	// served by the same runtime.
+	IsolationNone Isolation = iota
+
+	// IsolationRestore snapshots the global object and top-level let, const
+	// and class bindings after the script has been evaluated, and restores them
+	// after every request. Modules first required during a request are removed from
+	// the require cache, so the next request evaluates them again. Properties of objects
+	// referenced by globals and module-level variables of modules required while the
+	// script has been evaluated are not restored.
+	IsolationRestore
+
+	// IsolationFreeze restores the snapshot like IsolationRestore and additionally
+	// deep-freezes the values the script has defined and the exports of the modules
+	// it has required, so that handlers can't mutate them. In strict mode such mutations throw a TypeError.
+	IsolationFreeze
+)
+
+// isolationShim returns an object with a check(restore) function, which returns
+// a list of global mutations since the snapshot has been taken.
+// Builtins are captured when the snapshot is taken, so that handlers
+// overwriting them can't break the check.
+const isolationShim = `(function(baseline, requestGlobals, getBindings, setBindings, bindingNames) {
+	const global = globalThis
+	const getOwnPropertyNames = Object.getOwnPropertyNames
+	const getOwnPropertyDescriptor = Object.getOwnPropertyDescriptor
+	const defineProperty = Object.defineProperty
+	const ownKeys = Reflect.ownKeys
+	const freeze = Object.freeze
+	const is = Object.is
+
+	const props = new Map()
+	for (const n of getOwnPropertyNames(global)) {
+		props.set(n, getOwnPropertyDescriptor(global, n))
+	}
+	const bindings = getBindings()
+
+	const sameDescriptor = (a, b) =>
+		is(a.value, b.value) && a.get === b.get && a.set === b.set &&
+		a.writable === b.writable && a.enumerable === b.enumerable && a.configurable === b.configurable
+
+	const restoreProperty = (n, d) => {
+		try {
+			defineProperty(global, n, d)
+		} catch (e) {
+			// non-configurable properties can't be restored
+		}
+	}
+
+	return {
+		check(restore) {
+			const mutations = []
+
+			for (const n of getOwnPropertyNames(global)) {
+				if (props.has(n) || requestGlobals.has(n)) {
+					continue
+				}
+				mutations.push("added " + n)
+				if (restore) {
+					delete global[n]
+				}
+			}
+
+			for (const [n, d] of props) {
+				const current = getOwnPropertyDescriptor(global, n)
+				if (current === undefined) {
+					mutations.push("deleted " + n)
+				} else if (!sameDescriptor(current, d)) {
+					mutations.push("changed " + n)
+				} else {
+					continue
+				}
+				if (restore) {
+					restoreProperty(n, d)
+				}
+			}
+
+			const currentBindings = getBindings()
+			let reassigned = false
+			for (let i = 0; i < bindings.length; i++) {
+				if (!is(currentBindings[i], bindings[i])) {
+					mutations.push("reassigned " + bindingNames[i])
+					reassigned = true
+				}
+			}
+			if (restore && reassigned) {
+				setBindings(bindings)
+			}
+
+			return mutations
+		},
+
+		freeze(modules) {
+			// builtins and values provided by Go are not frozen
+			const skip = new Set()
+			for (const n of baseline) {
+				const d = props.get(n)
+				if (d !== undefined && "value" in d) {
+					skip.add(d.value)
+				}
+			}
+
+			const deepFreeze = (v) => {
+				if ((typeof v !== "object" && typeof v !== "function") || v === null || skip.has(v)) {
+					return
+				}
+				skip.add(v)
+				try {
+					freeze(v)
+				} catch (e) {
+					// Go values can't be frozen
+					return
+				}
+				for (const k of ownKeys(v)) {
+					const d = getOwnPropertyDescriptor(v, k)
+					if (d !== undefined && "value" in d) {
+						deepFreeze(d.value)
+					}
+				}
+			}
+
+			for (const [n, d] of props) {
+				if (!baseline.includes(n) && "value" in d) {
+					deepFreeze(d.value)
+				}
+			}
+			for (const b of bindings) {
+				deepFreeze(b)
+			}
+			for (const m of modules) {
+				deepFreeze(m)
+			}
+		},
+	}
+})`
+
+type isolation struct {
+	check  goja.Callable
+	freeze goja.Callable
+
+	// modules are the exports of the modules required while the script has been evaluated
+	modules map[string]goja.Value
+}
+
+// globalNames returns own property names of the global object.
+func globalNames(rt *goja.Runtime) ([]string, error) {
+	v, err := rt.RunString("Object.getOwnPropertyNames(globalThis)")
+	if err != nil {
+		return nil, fmt.Errorf("could not get global property names: %w", err)
+	}
+
+	names := []string{}
+	err = rt.ExportTo(v, &names)
+	if err != nil {
+		return nil, fmt.Errorf("could not export global property names: %w", err)
+	}
+
+	return names, nil
+}
+
+// newIsolation takes the snapshot of the global state of rt. baseline contains
+// global property names that existed before the script has been evaluated.
+func newIsolation(rt *goja.Runtime, baseline []string, requestGlobals []string, bindings []compiler.Binding) (*isolation, error) {
+	names := []string{}
+	mutable := []string{}
+	for i, b := range bindings {
+		names = append(names, b.Name)
+		if b.Mutable {
+			mutable = append(mutable, fmt.Sprintf("%s = values[%d]", b.Name, i))
+		}
+	}
+
+	getBindings, err := rt.RunString(fmt.Sprintf("(function() { return [%s] })", strings.Join(names, ", ")))
+	if err != nil {
+		return nil, fmt.Errorf("could not create bindings getter: %w", err)
+	}
+
+	setBindings, err := rt.RunString(fmt.Sprintf("(function(values) { %s })", strings.Join(mutable, "; ")))
+	if err != nil {
+		return nil, fmt.Errorf("could not create bindings setter: %w", err)
+	}
+
+	shim, err := rt.RunString(isolationShim)
+	if err != nil {
+		return nil, fmt.Errorf("could not evaluate isolation shim: %w", err)
+	}
+
+	create, isFunction := goja.AssertFunction(shim)
+	if !isFunction {
+		return nil, fmt.Errorf("isolation shim is not a function")
+	}
+
+	requestGlobalsSet, err := rt.New(rt.Get("Set"), rt.ToValue(requestGlobals))
+	if err != nil {
+		return nil, fmt.Errorf("could not create set of request globals: %w", err)
+	}
+
+	iso, err := create(nil, rt.ToValue(baseline), requestGlobalsSet, getBindings, setBindings, rt.ToValue(names))
+	if err != nil {
+		return nil, fmt.Errorf("could not create isolation: %w", err)
+	}
+
+	isoObject := iso.ToObject(rt)
+
+	check, isFunction := goja.AssertFunction(isoObject.Get("check"))
+	if !isFunction {
+		return nil, fmt.Errorf("isolation check is not a function")
+	}
+
+	freeze, isFunction := goja.AssertFunction(isoObject.Get("freeze"))
+	if !isFunction {
+		return nil, fmt.Errorf("isolation freeze is not a function")
+	}
+
+	modules, err := require.Exports(rt)
+	if err != nil {
+		return nil, fmt.Errorf("could not get required modules: %w", err)
+	}
+
+	return &isolation{check: check, freeze: freeze, modules: modules}, nil
+}
+
+// freezeAll deep-freezes the values the script has defined and the exports of the modules it has required.
+func (i *isolation) freezeAll(rt *goja.Runtime) error {
+	exports := []goja.Value{}
+	for _, e := range i.modules {
+		exports = append(exports, e)
+	}
+
+	_, err := i.freeze(nil, rt.ToValue(exports))
+	return err
+}
+
+// mutations returns global mutations since the snapshot, restoring them when restore is set.
+// Restoring removes the modules first required since the snapshot from the require cache.
+func (i *isolation) mutations(rt *goja.Runtime, restore bool) ([]string, error) {
+	v, err := i.check(nil, rt.ToValue(restore))
+	if err != nil {
+		return nil, fmt.Errorf("could not check global mutations: %w", err)
+	}
+
+	mutations := []string{}
+	err = rt.ExportTo(v, &mutations)
+	if err != nil {
+		return nil, fmt.Errorf("could not export global mutations: %w", err)
+	}
+
+	if restore {
+		removed, err := require.Forget(rt, i.modules)
+		if err != nil {
+			return nil, fmt.Errorf("could not reset required modules: %w", err)
+		}
+		for _, id := range removed {
+			mutations = append(mutations, "required "+id)
+		}
+	}
+
+	return mutations, nil
+}
@@ -0,0 +1,121 @@
+package jshandler
+
+import (
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/draganm/go-lean/common/compiler"
+	leanrequire "github.com/draganm/go-lean/require"
+	"github.com/stretchr/testify/require"
+)
+
+var isolationLibs = map[string]string{
+	"/lib/counter.js":  "let count = 0\nexports.next = () => ++count",
+	"/lib/registry.js": "exports.items = []",
+}
+
+const isolationScript = `
+let count = 0
+require("/lib/registry")
+
+function handler() {
+	count++
+	globalThis.leaked = (globalThis.leaked || 0) + 1
+	return [count, globalThis.leaked, require("/lib/counter").next()]
+}
+
+function register(item) {
+	try {
+		require("/lib/registry").items.push(item)
+	} catch (e) {
+		return e.constructor.name
+	}
+	return require("/lib/registry").items.length
+}
+`
+
+// evalIsolated evaluates the script on a new runtime the way handlers are evaluated,
+// with require() provided for every request, and takes the snapshot.
+func evalIsolated(t *testing.T) (*goja.Runtime, *isolation) {
+	require := require.New(t)
+
+	b := leanrequire.NewBuilder()
+	for pth, src := range isolationLibs {
+		src := src
+		require.True(b.Consume(pth, func() ([]byte, error) { return []byte(src), nil }))
+	}
+	provider, err := b.Build()
+	require.NoError(err)
+
+	rt := goja.New()
+	baseline, err := globalNames(rt)
+	require.NoError(err)
+
+	err = rt.Set("require", func(name string) (goja.Value, error) {
+		return provider(rt, name)
+	})
+	require.NoError(err)
+
+	bindings, err := compiler.LexicalBindings("/web/@GET.js", isolationScript)
+	require.NoError(err)
+
+	prog, err := compiler.Compile("/web/@GET.js", isolationScript, false)
+	require.NoError(err)
+	require.NoError(compiler.RunScript(rt, prog))
+
+	iso, err := newIsolation(rt, baseline, []string{"require"}, bindings)
+	require.NoError(err)
+
+	return rt, iso
+}
+
+func call(t *testing.T, rt *goja.Runtime, fn string, args ...any) any {
+	f, isFunction := goja.AssertFunction(rt.Get(fn))
+	require.True(t, isFunction)
+
+	vals := []goja.Value{}
+	for _, a := range args {
+		vals = append(vals, rt.ToValue(a))
+	}
+
+	v, err := f(nil, vals...)
+	require.NoError(t, err)
+	return v.Export()
+}
+
+func TestIsolation(t *testing.T) {
+	t.Run("restore resets globals, bindings and modules required by the request", func(t *testing.T) {
+		require := require.New(t)
+		rt, iso := evalIsolated(t)
+
+		for i := 0; i < 3; i++ {
+			require.Equal([]any{int64(1), int64(1), int64(1)}, call(t, rt, "handler"))
+
+			mutations, err := iso.mutations(rt, true)
+			require.NoError(err)
+			require.Equal([]string{"added leaked", "reassigned count", "required /lib/counter.js"}, mutations)
+		}
+	})
+
+	t.Run("without restoring, state is kept", func(t *testing.T) {
+		require := require.New(t)
+		rt, iso := evalIsolated(t)
+
+		require.Equal([]any{int64(1), int64(1), int64(1)}, call(t, rt, "handler"))
+		_, err := iso.mutations(rt, false)
+		require.NoError(err)
+
+		require.Equal([]any{int64(2), int64(2), int64(2)}, call(t, rt, "handler"))
+		require.Equal(int64(1), call(t, rt, "register", "a"))
+		require.Equal(int64(2), call(t, rt, "register", "b"))
+	})
+
+	t.Run("freeze freezes exports of modules required by the script", func(t *testing.T) {
+		require := require.New(t)
+		rt, iso := evalIsolated(t)
+
+		require.NoError(iso.freezeAll(rt))
+
+		require.Equal("TypeError", call(t, rt, "register", "a"))
+	})
+}
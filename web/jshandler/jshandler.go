@@ -1,14 +1,20 @@
 package jshandler
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
+	"reflect"
 	"sync"
 
 	"github.com/dop251/goja"
+	"github.com/draganm/go-lean/common/compiler"
+	"github.com/draganm/go-lean/common/eventloop"
 	"github.com/draganm/go-lean/common/globals"
-	"github.com/draganm/go-lean/common/goja/fieldmapper"
+	"github.com/draganm/go-lean/common/jsruntime"
+	"github.com/draganm/go-lean/common/jsstack"
+	"github.com/draganm/go-lean/common/limits"
 	"github.com/draganm/go-lean/web/types"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-logr/logr"
@@ -27,28 +33,80 @@ func (s *statusError) Error() string {
 	return fmt.Sprintf("%d: %s", s.code, s.message)
 }
 
+// ReturnStatus is the `returnStatus` global of handlers. It throws an error
+// that ends the handler with the status code and message.
+func ReturnStatus(code int, message string) error {
+	return &statusError{code: code, message: message}
+}
+
 var tracer = otel.Tracer("github.com/draganm/go-lean/leanweb/jshandler")
 
+// InjectableTypes can be injected into providers of handler globals.
+// While the script is being evaluated, only the context, runtime and event loop are available.
+var InjectableTypes = []reflect.Type{
+	globals.TypeOf[context.Context](),
+	globals.TypeOf[*http.Request](),
+	globals.TypeOf[http.ResponseWriter](),
+	globals.TypeOf[types.HandlerPath](),
+	globals.TypeOf[*goja.Runtime](),
+	globals.TypeOf[*eventloop.EventLoop](),
+}
+
+type instance struct {
+	rt   *goja.Runtime
+	loop *eventloop.EventLoop
+	iso  *isolation
+}
+
 func New(
 	log logr.Logger,
 	requestPath string,
+	fileName string,
 	code string,
-	gl globals.Globals,
+	env *jsruntime.Environment,
+	opts Options,
 ) (http.HandlerFunc, error) {
 
-	prog, err := goja.Compile(requestPath, code, true)
+	gl := env.Globals
+
+	prog, err := compiler.Compile(fileName, code, true)
 	if err != nil {
-		return nil, fmt.Errorf("could not compile %s: %w", requestPath, err)
+		return nil, fmt.Errorf("could not compile %s: %w", fileName, err)
 	}
 
-	createInstance := func() (*goja.Runtime, error) {
-		rt := goja.New()
-		rt.SetFieldNameMapper(fieldmapper.FallbackFieldMapper{})
+	trackGlobals := opts.Isolation != IsolationNone || opts.LogGlobalMutations
 
-		// I'm aware that not everything here will be wired properly, but
-		// this is necessary in order not to have to treat require()
-		// as a special case
-		wired, err := gl.AutoWire(rt)
+	var bindings []compiler.Binding
+	if trackGlobals {
+		bindings, err = compiler.LexicalBindings(fileName, code)
+		if err != nil {
+			return nil, fmt.Errorf("could not find top-level bindings of %s: %w", fileName, err)
+		}
+	}
+
+	requestGlobals := []string{}
+	for k := range gl {
+		requestGlobals = append(requestGlobals, k)
+	}
+
+	createInstance := func() (*instance, error) {
+		rt, loop, err := env.New()
+		if err != nil {
+			return nil, err
+		}
+
+		var baseline []string
+		if trackGlobals {
+			baseline, err = globalNames(rt)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// globals using the request are left out while the script is evaluated,
+		// they are wired for every request. This is necessary in order not to
+		// have to treat require() as a special case
+		wired, finish, err := gl.AutoWireFinish(InjectableTypes, rt, loop, logr.NewContext(context.Background(), log))
 		if err != nil {
 			return nil, fmt.Errorf("could not autowire globals: %w", err)
 		}
@@ -56,17 +114,18 @@ func New(
 		for k, v := range wired {
 			err = rt.GlobalObject().Set(k, v)
 			if err != nil {
-				return nil, fmt.Errorf("could not set global %s: %w", k, err)
+				err = fmt.Errorf("could not set global %s: %w", k, err)
+				return nil, errors.Join(err, finish(err))
 			}
 		}
 
-		rt.GlobalObject().Set("returnStatus", func(code int, message string) error {
-			return &statusError{code: code, message: message}
-		})
-
-		_, err = rt.RunProgram(prog)
+		err = compiler.RunScript(rt, prog)
+		ferr := finish(err)
 		if err != nil {
-			return nil, fmt.Errorf("could not eval handler script: %w", err)
+			return nil, fmt.Errorf("could not eval handler script: %w", errors.Join(err, ferr))
+		}
+		if ferr != nil {
+			return nil, fmt.Errorf("could not finish globals: %w", ferr)
 		}
 
 		// delete autowired globals, they'll be provided again at request time
@@ -81,7 +140,23 @@ func New(
 			return nil, fmt.Errorf("could not find handler() function")
 		}
 
-		return rt, nil
+		inst := &instance{rt: rt, loop: loop}
+
+		if trackGlobals {
+			inst.iso, err = newIsolation(rt, baseline, requestGlobals, bindings)
+			if err != nil {
+				return nil, err
+			}
+
+			if opts.Isolation == IsolationFreeze {
+				err = inst.iso.freezeAll(rt)
+				if err != nil {
+					return nil, fmt.Errorf("could not freeze globals: %w", err)
+				}
+			}
+		}
+
+		return inst, nil
 	}
 
 	canary, err := createInstance()
@@ -89,6 +164,11 @@ func New(
 		return nil, fmt.Errorf("invalid handler %s: %w", requestPath, err)
 	}
 
+	cfg, err := readConfig(canary.rt, opts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config of handler %s: %w", requestPath, err)
+	}
+
 	rtPool := &sync.Pool{
 		New: func() any {
 			v, err := createInstance()
@@ -101,7 +181,7 @@ func New(
 
 	rtPool.Put(canary)
 
-	return otelhttp.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
 		ctx, span := tracer.Start(r.Context(), fmt.Sprintf("%s %s", r.Method, requestPath),
 			trace.WithAttributes(
@@ -112,11 +192,25 @@ func New(
 		defer span.End()
 		r = r.WithContext(ctx)
 
+		routeContext := chi.RouteContext(r.Context())
+
+		params := map[string]string{}
+		urlParams := routeContext.URLParams
+		for i, pn := range urlParams.Keys {
+			params[pn] = urlParams.Values[i]
+		}
+
 		log := logr.FromContextOrDiscard(r.Context())
-		rt := rtPool.Get().(*goja.Runtime)
-		defer rtPool.Put(rt)
+		r = r.WithContext(logr.NewContext(r.Context(), log.WithValues("params", params)))
+
+		inst := rtPool.Get().(*instance)
+		defer rtPool.Put(inst)
+		rt := inst.rt
+
+		// the script writes through the limited writer, errors are written directly
+		jsw := env.Limits.LimitResponse(w)
 
-		autowired, err := gl.AutoWire(rt, r.Context(), r, w, types.HandlerPath(requestPath))
+		autowired, finish, err := gl.AutoWireFinish(InjectableTypes, rt, inst.loop, r.Context(), r, jsw, types.HandlerPath(requestPath))
 		if err != nil {
 			http.Error(w, "internal error", http.StatusInternalServerError)
 			log.Error(err, "could not autowire globals")
@@ -127,43 +221,100 @@ func New(
 			err = rt.GlobalObject().Set(k, v)
 			if err != nil {
 				http.Error(w, "internal error", http.StatusInternalServerError)
-				log.Error(err, "could not set global %s: %w", k, err)
+				log.Error(errors.Join(err, finish(err)), "could not set global", "global", k)
 				return
 			}
 		}
 
 		v := rt.Get("handler")
 
-		routeContext := chi.RouteContext(r.Context())
-
-		params := map[string]string{}
-		urlParams := routeContext.URLParams
-		for i, pn := range urlParams.Keys {
-			params[pn] = urlParams.Values[i]
-		}
-
-		err = rt.GlobalObject().Set("log", log.WithValues("params", params))
-		if err != nil {
-			http.Error(w, "internal error", http.StatusInternalServerError)
-			log.Error(err, "could set log global")
-			return
-		}
-
 		fn, isFunction := goja.AssertFunction(v)
 		if !isFunction {
+			err = errors.New("could not find handler function")
 			http.Error(w, "internal error", http.StatusInternalServerError)
-			log.Error(err, "could not find handler function")
+			log.Error(errors.Join(err, finish(err)), "could not find handler function")
 			return
 		}
 
 		// remove globals at the end of the request before it's returned to the pool
 		defer func() {
+			if inst.iso != nil {
+				mutations, err := inst.iso.mutations(rt, opts.Isolation != IsolationNone)
+				if err != nil {
+					log.Error(err, "could not check global mutations")
+				} else if opts.LogGlobalMutations && len(mutations) > 0 {
+					log.Info("handler mutated global state", "mutations", mutations)
+				}
+			}
+
 			for g := range gl {
 				rt.GlobalObject().Delete(g)
 			}
 		}()
 
-		_, err = fn(nil, rt.ToValue(w), rt.ToValue(r), rt.ToValue(params))
+		runCtx, budget := env.Limits.StartBudget(r.Context(), rt)
+
+		// interrupt the handler when the request is cancelled or times out
+		handlerDone := make(chan struct{})
+		watcherDone := make(chan struct{})
+		go func() {
+			defer close(watcherDone)
+			select {
+			case <-handlerDone:
+			case <-runCtx.Done():
+				rt.Interrupt(context.Cause(runCtx))
+			}
+		}()
+
+		// async handlers are awaited, including all timers and promises they've started
+		_, err = inst.loop.Run(runCtx, func(rt *goja.Runtime) (goja.Value, error) {
+			return fn(nil, rt.ToValue(jsw), rt.ToValue(r), rt.ToValue(params))
+		})
+
+		close(handlerDone)
+		<-watcherDone
+		err = budget.Stop(err)
+		rt.ClearInterrupt()
+
+		// the script might have caught the error of the rejected write
+		lw, isLimited := jsw.(*limits.ResponseWriter)
+		if err == nil && isLimited && lw.Exceeded() {
+			err = limits.ErrResponseTooLarge
+		}
+
+		// providers release their resources depending on the outcome,
+		// e.g. a transaction is committed only if the handler has succeeded
+		ferr := finish(err)
+		if ferr != nil {
+			log.Error(ferr, "could not finish request globals")
+			if err == nil {
+				err = ferr
+			}
+		}
+
+		violation := limits.Report(fileName, err)
+		if violation != nil {
+			frames := jsstack.FromError(err)
+			span.RecordError(err, trace.WithAttributes(attribute.String("exception.stacktrace", jsstack.Format(frames))))
+			log.Error(err, "handler exceeded resource limit", "limit", violation.Error(), "jsStack", frames)
+			switch {
+			case isLimited && lw.HeaderWritten():
+				// status has already been sent
+			case violation == limits.ErrTimeBudgetExceeded:
+				http.Error(w, "handler exceeded its time budget", http.StatusServiceUnavailable)
+			default:
+				http.Error(w, "internal error", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		if errors.Is(err, context.DeadlineExceeded) {
+			frames := jsstack.FromError(err)
+			span.RecordError(err, trace.WithAttributes(attribute.String("exception.stacktrace", jsstack.Format(frames))))
+			http.Error(w, "handler timed out", http.StatusServiceUnavailable)
+			log.Error(err, "handler timed out", "jsStack", frames)
+			return
+		}
 
 		// check for statusError exception being thrown
 		exc := &goja.Exception{}
@@ -182,11 +333,18 @@ func New(
 			}
 		}
 		if err != nil {
-			span.RecordError(err)
+			frames := jsstack.FromError(err)
+			span.RecordError(err, trace.WithAttributes(attribute.String("exception.stacktrace", jsstack.Format(frames))))
+			log.Error(err, "handler error", "jsStack", frames)
+			if opts.DevMode {
+				writeErrorPage(w, err, frames, opts.SourceLookup)
+				return
+			}
 			http.Error(w, "internal error", http.StatusInternalServerError)
-			log.Error(err, "handler error")
 			return
 		}
 
-	}), "golean").ServeHTTP, nil
+	})
+
+	return otelhttp.NewHandler(cfg.wrap(opts, handler), "golean").ServeHTTP, nil
 }
@@ -0,0 +1,81 @@
+package types
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// TemplatesRoot is the directory of templates that are not part of the web pages,
+// e.g. emails and reports. They can be rendered in every context.
+const TemplatesRoot = "/templates"
+
+// StandaloneHandlerPath is the handler path templates are resolved relative to outside of handlers,
+// so that names are relative to TemplatesRoot.
+const StandaloneHandlerPath HandlerPath = TemplatesRoot + "/index"
+
+// TemplateName returns the name of a template file: files under /web are named by
+// their path without /web, files under TemplatesRoot keep it. Other files aren't templates.
+func TemplateName(pth string) (string, bool) {
+	switch {
+	case strings.HasPrefix(pth, "/web/"):
+		return strings.TrimPrefix(pth, "/web"), true
+	case strings.HasPrefix(pth, TemplatesRoot+"/"):
+		return pth, true
+	default:
+		return "", false
+	}
+}
+
+// TemplateFiles maps names of templates to the files they are read from.
+type TemplateFiles map[string]string
+
+// Add records the file of the template. `/web/templates/a.mustache` and `/templates/a.mustache`
+// have the same name, so an error is returned if another file already has the name.
+func (tf TemplateFiles) Add(name, file string) error {
+	other, found := tf[name]
+	if found && other != file {
+		return fmt.Errorf("templates %s and %s have the same name %s", other, file, name)
+	}
+	tf[name] = file
+	return nil
+}
+
+// LayoutName is the name of the templates used as the default layout of their directory and its subdirectories.
+const LayoutName = "_layout"
+
+// Layout returns the absolute name of the layout the template with the absolute name is rendered in,
+// empty if it is rendered on its own. option is the layout option of the render: the name of a template,
+// resolved with resolve, or false for no layout. Without it, the nearest LayoutName template for which
+// exists returns true is used.
+func Layout(name string, option any, resolve func(name string) string, exists func(name string) bool) (string, error) {
+	switch l := option.(type) {
+	case nil:
+		return nearestLayout(name, exists), nil
+	case bool:
+		if l {
+			return "", fmt.Errorf("layout must be a template name or false")
+		}
+		return "", nil
+	case string:
+		return resolve(l), nil
+	default:
+		return "", fmt.Errorf("layout must be a template name or false")
+	}
+}
+
+// nearestLayout returns the LayoutName template closest to the template, which is never its own layout.
+func nearestLayout(name string, exists func(name string) bool) string {
+	dir := path.Dir(name)
+	for {
+		candidate := path.Join(dir, LayoutName)
+		if candidate != name && exists(candidate) {
+			return candidate
+		}
+		// templates in TemplatesRoot don't use the layouts of the pages
+		if dir == "/" || dir == TemplatesRoot {
+			return ""
+		}
+		dir = path.Dir(dir)
+	}
+}
@@ -24,4 +24,4 @@ var (
 	}, []string{"status", "method", "path"})
 )
 
-var handlerRegexp = regexp.MustCompile(`^@([A-Z]+).js$`)
+var handlerRegexp = regexp.MustCompile(`^@([A-Z]+).(?:js|ts)$`)
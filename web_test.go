@@ -16,7 +16,7 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-//go:embed fixtures
+//go:embed all:fixtures
 var simple embed.FS
 
 func TestServingStaticFiles(t *testing.T) {